@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Popcore/hangmango/pkg/certs"
+)
+
+func init() {
+	rootCmd.AddCommand(certCmd())
+}
+
+func certCmd() *cobra.Command {
+	var outDir string
+	var name string
+	var commonName string
+	var hosts []string
+	var validFor time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "cert",
+		Short: "generates a self-signed certificate for development",
+		Long: `generates a self-signed certificate and its private key as <name>.pem and <name>-key.pem.
+The certificate can be used by the server (--tls-cert, --tls-key), trusted by clients (--ca)
+or used as a client certificate (--cert, --key) whose common name becomes the player user name
+on servers started with --client-ca.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			certPEM, keyPEM, err := certs.GenerateSelfSigned(commonName, hosts, validFor)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			certFile := filepath.Join(outDir, name+".pem")
+			keyFile := filepath.Join(outDir, name+"-key.pem")
+
+			err = ioutil.WriteFile(certFile, certPEM, 0644)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			err = ioutil.WriteFile(keyFile, keyPEM, 0600)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			log.Printf("certificate written to %s, private key written to %s", certFile, keyFile)
+		},
+	}
+	cmd.Flags().StringVarP(&outDir, "out", "o", ".", "the output directory")
+	cmd.Flags().StringVarP(&name, "name", "n", "hangmango", "the name of the generated files")
+	cmd.Flags().StringVar(&commonName, "cn", "hangmango", "the certificate common name")
+	cmd.Flags().StringSliceVar(&hosts, "host", []string{"localhost", "127.0.0.1"}, "the hosts the certificate is valid for")
+	cmd.Flags().DurationVar(&validFor, "valid-for", 365*24*time.Hour, "the certificate validity")
+
+	return cmd
+}
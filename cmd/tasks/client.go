@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/Popcore/hangmango/pkg/certs"
 	"github.com/Popcore/hangmango/pkg/client"
 )
 
@@ -14,20 +15,45 @@ func init() {
 
 func clientCmd() *cobra.Command {
 	var port string
+	var useWebSocket bool
+	var useTLS bool
+	var caFile, certFile, keyFile string
+	var token string
 
 	cmd := &cobra.Command{
 		Use:   "client",
 		Short: "starts a new client session",
 		Run: func(cmd *cobra.Command, args []string) {
-			c, err := client.New(port)
+			var opts []client.Option
+			if useWebSocket {
+				opts = append(opts, client.WithWebSocket())
+			}
+
+			if useTLS {
+				config, err := certs.ClientConfig(caFile, certFile, keyFile)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				opts = append(opts, client.WithTLS(config))
+			}
+
+			c, err := client.New(port, opts...)
 			if err != nil {
 				log.Fatal(err)
 			}
 
+			c.Token = token
 			c.Play()
 		},
 	}
-	cmd.Flags().StringVarP(&port, "port", "p", "9090", "the server port")
+	cmd.Flags().StringVarP(&port, "port", "p", "9090", "the server port, or the server unix socket as unix://<path>")
+	cmd.Flags().BoolVarP(&useWebSocket, "websocket", "w", false, "connect to the server websocket port instead of the tcp one")
+	cmd.Flags().BoolVar(&useTLS, "tls", false, "secure the connection with TLS")
+	cmd.Flags().StringVar(&token, "token", "", "the session token received on login, used to get back to the session after a disconnection")
+	cmd.Flags().StringVar(&caFile, "ca", "", "the CA certificate used to verify the server. System roots are used if empty")
+	cmd.Flags().StringVar(&certFile, "cert", "", "the client certificate presented to servers requiring mutual TLS")
+	cmd.Flags().StringVar(&keyFile, "key", "", "the private key of the client certificate")
 
 	return cmd
 }
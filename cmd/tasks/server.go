@@ -1,9 +1,17 @@
 package tasks
 
 import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
 	"github.com/spf13/cobra"
 
+	"github.com/Popcore/hangmango/pkg/certs"
 	"github.com/Popcore/hangmango/pkg/server"
+	"github.com/Popcore/hangmango/pkg/server/handlers"
+	"github.com/Popcore/hangmango/pkg/words"
 )
 
 func init() {
@@ -12,17 +20,71 @@ func init() {
 
 func serverCmd() *cobra.Command {
 	var port string
-	var verbose bool
+	var wsPort string
+	var httpPort string
+	var socketPath, socketMode string
+	var idleTimeout, writeTimeout, sessionTTL time.Duration
+	var verbose, noRepeat bool
+	var certFile, keyFile, clientCAFile string
+	var wordsDir, configFile, wordSource string
+	var reloadInterval time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "server",
 		Short: "starts a new game server",
 		Run: func(cmd *cobra.Command, args []string) {
 			s := server.New(port, verbose)
+			s.WSPort = wsPort
+			s.HTTPPort = httpPort
+			s.SocketPath = socketPath
+			s.System.IdleTimeout = idleTimeout
+			s.System.WriteTimeout = writeTimeout
+			s.System.Sessions = handlers.NewSessions(sessionTTL)
+
+			mode, err := strconv.ParseUint(socketMode, 8, 32)
+			if err != nil {
+				log.Fatalf("invalid socket mode %s: %v", socketMode, err)
+			}
+			s.SocketMode = os.FileMode(mode)
+
+			s.WordsDir = wordsDir
+			s.ConfigFile = configFile
+			s.ReloadInterval = reloadInterval
+
+			source, err := words.ParseSource(wordSource)
+			if err != nil {
+				log.Fatal(err)
+			}
+			s.System.Source = source
+			s.System.NoRepeat = noRepeat
+
+			if certFile != "" || keyFile != "" {
+				config, err := certs.ServerConfig(certFile, keyFile, clientCAFile)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				s.TLSConfig = config
+			}
 			s.Start()
 		},
 	}
 	cmd.Flags().StringVarP(&port, "port", "p", "9090", "the server port")
+	cmd.Flags().StringVar(&wsPort, "ws-port", "", "the websocket port. WebSocket connections are disabled if empty")
+	cmd.Flags().StringVar(&httpPort, "http-port", "", "the http api port. The http api is disabled if empty")
+	cmd.Flags().StringVar(&socketPath, "socket", "", "the path of a unix domain socket to listen on. Disabled if empty")
+	cmd.Flags().StringVar(&socketMode, "socket-mode", "0600", "the octal file mode of the unix domain socket")
+	cmd.Flags().DurationVar(&idleTimeout, "idle-timeout", 10*time.Minute, "how long a session waits for the next request before being closed. 0 disables it")
+	cmd.Flags().DurationVar(&writeTimeout, "write-timeout", 10*time.Second, "how long sending a response can take before the session is closed. 0 disables it")
+	cmd.Flags().DurationVar(&sessionTTL, "session-ttl", handlers.DefaultSessionTTL, "how long players can reconnect to their session after a disconnection")
+	cmd.Flags().StringVar(&certFile, "tls-cert", "", "the TLS certificate. Connections are secured with TLS if set")
+	cmd.Flags().StringVar(&keyFile, "tls-key", "", "the private key of the TLS certificate")
+	cmd.Flags().StringVar(&clientCAFile, "client-ca", "", "the CA used to verify client certificates. Enables mutual TLS if set")
+	cmd.Flags().StringVar(&wordsDir, "words", "", "a directory of .json and .txt word packs. Only the heroes pack is available if empty")
+	cmd.Flags().StringVar(&wordSource, "word-source", words.SourceUniform, "how words are picked: uniform, weighted by frequency, sequential or seeded:<seed> for a reproducible sequence")
+	cmd.Flags().BoolVar(&noRepeat, "no-repeat", true, "do not give players a word they already played until they played the whole pack")
+	cmd.Flags().StringVar(&configFile, "config", "", "a JSON file of settings reloaded on SIGHUP: idle_timeout, write_timeout, session_ttl and max_request_size")
+	cmd.Flags().DurationVar(&reloadInterval, "reload-interval", 0, "how often to check the word packs and the config for changes to reload. 0 disables it, SIGHUP always reloads them")
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", true, "print logs to stout")
 
 	return cmd
@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Popcore/hangmango/pkg/words"
+)
+
+func init() {
+	rootCmd.AddCommand(wordsCmd())
+}
+
+func wordsCmd() *cobra.Command {
+	var wordsDir string
+	var top int
+
+	cmd := &cobra.Command{
+		Use:   "words",
+		Short: "lists the hardest and easiest words of each word pack",
+		Long: `lists the hardest and easiest words of each word pack, the heroes pack and the packs of
+the --words directory, with their difficulty score from 0 (easiest) to 100 (hardest).
+Words are scored from their length, distinct letters and letter rarity. The solve rates
+that adaptive games also take into account are only known to a running server.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if top < 1 {
+				log.Fatalf("--top must list at least one word, got %d", top)
+			}
+
+			var packs []*words.Pack
+			if wordsDir != "" {
+				var err error
+
+				packs, err = words.LoadDir(wordsDir)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			library := words.NewLibrary(packs...)
+			for _, category := range library.Categories() {
+				pack, _ := library.Pack(category)
+				scores := pack.Scores(nil)
+
+				n := top
+				if n > len(scores) {
+					n = len(scores)
+				}
+
+				fmt.Printf("%s (%d words)\n", category, len(scores))
+
+				fmt.Println("  hardest:")
+				for _, s := range scores[:n] {
+					fmt.Printf("    %3.0f %s\n", s.Score*100, s.Word.Text)
+				}
+
+				fmt.Println("  easiest:")
+				for i := len(scores) - 1; i >= len(scores)-n; i-- {
+					fmt.Printf("    %3.0f %s\n", scores[i].Score*100, scores[i].Word.Text)
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVar(&wordsDir, "words", "", "a directory of .json and .txt word packs. Only the heroes pack is reported if empty")
+	cmd.Flags().IntVarP(&top, "top", "n", 5, "the number of hardest and easiest words to list")
+
+	return cmd
+}
@@ -0,0 +1,74 @@
+package certs
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert generates a self-signed certificate named name in dir and returns the
+// paths of the certificate and key files.
+func writeCert(t *testing.T, dir, name string) (string, string) {
+	certPEM, keyPEM, err := GenerateSelfSigned(name, []string{"localhost", "127.0.0.1"}, time.Hour)
+	assert.Nil(t, err)
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+
+	assert.Nil(t, ioutil.WriteFile(certFile, certPEM, 0644))
+	assert.Nil(t, ioutil.WriteFile(keyFile, keyPEM, 0600))
+
+	return certFile, keyFile
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	serverCert, serverKey := writeCert(t, dir, "server")
+	clientCert, clientKey := writeCert(t, dir, "alice")
+
+	serverConfig, err := ServerConfig(serverCert, serverKey, clientCert)
+	assert.Nil(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, serverConfig.ClientAuth)
+
+	clientConfig, err := ClientConfig(serverCert, clientCert, clientKey)
+	assert.Nil(t, err)
+	clientConfig.ServerName = "localhost"
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	srv := tls.Server(serverConn, serverConfig)
+	cli := tls.Client(clientConn, clientConfig)
+
+	errs := make(chan error)
+	go func() {
+		errs <- cli.Handshake()
+	}()
+
+	assert.Nil(t, srv.Handshake())
+	assert.Nil(t, <-errs)
+	assert.Equal(t, "alice", PeerCommonName(srv.ConnectionState()))
+	assert.Equal(t, "server", PeerCommonName(cli.ConnectionState()))
+}
+
+func TestInvalidCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, ioutil.WriteFile(caFile, []byte("not a certificate"), 0644))
+
+	_, err = ClientConfig(caFile, "", "")
+	assert.Equal(t, ErrorInvalidCA, err)
+}
@@ -2,31 +2,83 @@ package client
 
 import (
 	"bufio"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Popcore/hangmango/pkg/client/drawing"
 	"github.com/Popcore/hangmango/pkg/game"
 	"github.com/Popcore/hangmango/pkg/messages"
+	"github.com/Popcore/hangmango/pkg/websocket"
+)
+
+// unixScheme prefixes the address of servers listening on a Unix domain socket.
+const unixScheme = "unix://"
+
+var (
+	ErrUnixTransport = errors.New("websocket and TLS are not available over unix sockets")
 )
 
 // Client is responsible for connecting to the upstream server, transmitting
-// the player actions and managing the server responses.
+// the player actions and managing the server responses. Capabilities holds what
+// the server advertised during the handshake and is nil if the server predates it.
+// Token is the session token issued by the server on login. When it is set before
+// Play is called the client reconnects to that session instead of logging in. The
+// embedded mutex protects the requests sent by the keepalive from the ones sent on
+// behalf of the player.
 type Client struct {
-	Port    string
-	Output  io.Writer
-	Encoder *json.Encoder
-	Decoder *json.Decoder
+	sync.Mutex
+	Port         string
+	Output       io.Writer
+	Encoder      *json.Encoder
+	Decoder      *json.Decoder
+	Capabilities *messages.HandshakeResp
+	Token        string
+	lastID       uint64
+}
+
+// Option configures how New connects to the server.
+type Option func(*options)
+
+// options holds the connection settings configured by the Option functions.
+type options struct {
+	webSocket bool
+	tls       *tls.Config
 }
 
-// New returns a new client connected to the server and ready to play.
-func New(port string) (*Client, error) {
-	conn, err := net.Dial("tcp", fmt.Sprintf(":%s", port))
+// WithWebSocket makes the client connect to the server WebSocket listener rather
+// than to the raw TCP one.
+func WithWebSocket() Option {
+	return func(o *options) {
+		o.webSocket = true
+	}
+}
+
+// WithTLS secures the connection to the server using config.
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tls = config
+	}
+}
+
+// New returns a new client connected to the server and ready to play. port is either
+// the TCP port of the server or the path of its Unix domain socket prefixed with
+// unix://, e.g. unix:///tmp/hangmango.sock.
+func New(port string, opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	conn, err := dial(port, o)
 	if err != nil {
 		return nil, err
 	}
@@ -41,10 +93,46 @@ func New(port string) (*Client, error) {
 	}, nil
 }
 
+// dial connects to the server listening at port using the transport selected by o.
+func dial(port string, o options) (net.Conn, error) {
+	if strings.HasPrefix(port, unixScheme) {
+		if o.webSocket || o.tls != nil {
+			return nil, ErrUnixTransport
+		}
+
+		return net.Dial("unix", strings.TrimPrefix(port, unixScheme))
+	}
+
+	if o.webSocket {
+		if o.tls != nil {
+			return websocket.DialTLS(fmt.Sprintf("wss://localhost:%s/", port), o.tls)
+		}
+
+		return websocket.Dial(fmt.Sprintf("ws://localhost:%s/", port))
+	}
+
+	if o.tls != nil {
+		return tls.Dial("tcp", fmt.Sprintf("localhost:%s", port), o.tls)
+	}
+
+	return net.Dial("tcp", fmt.Sprintf(":%s", port))
+}
+
 // Play starts a new gaming sessions. It authenticates the player and starts listening
 // to the commands issued.
-func (c Client) Play() error {
-	err := c.authenticateUser()
+func (c *Client) Play() error {
+	err := c.handshake()
+	if err != nil {
+		fmt.Fprintf(c.Output, "unexpected error negotiating protocol: %v", err)
+		os.Exit(1)
+	}
+
+	interval := c.keepAliveInterval()
+	if interval > 0 {
+		go c.keepAlive(interval)
+	}
+
+	err = c.authenticateUser()
 	if err != nil {
 		fmt.Fprintf(c.Output, "unexpected error authenticating user: %v", err)
 		os.Exit(1)
@@ -55,7 +143,7 @@ func (c Client) Play() error {
 
 // parseUserCommand parses the player's input and returns a PlayerReq message type that
 // can be encoded and sent to the server.
-func (c Client) parseUserCommand() messages.PlayerReq {
+func (c *Client) parseUserCommand() messages.PlayerReq {
 	var req messages.PlayerReq
 
 	for {
@@ -75,7 +163,7 @@ func (c Client) parseUserCommand() messages.PlayerReq {
 		}
 
 		if len(parts) > 1 {
-			joined := strings.Join(parts[1:len(parts)], "")
+			joined := strings.Join(parts[1:len(parts)], " ")
 			req.Value = strings.TrimRight(joined, "\r\n")
 		}
 
@@ -85,21 +173,54 @@ func (c Client) parseUserCommand() messages.PlayerReq {
 	return req
 }
 
-// encodeRequest encode PlayerReq messages as JSON payloads. It returns an error if the
-// encoding process fails.
-func (c Client) encodeRequest(req messages.PlayerReq) error {
-	return c.Encoder.Encode(req)
+// encodeRequest stamps req with a new request ID and encodes it as a JSON payload.
+// It returns the ID assigned to the request or an error if the encoding process fails.
+func (c *Client) encodeRequest(req messages.PlayerReq) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.lastID++
+	req.ID = strconv.FormatUint(c.lastID, 10)
+
+	return req.ID, c.Encoder.Encode(req)
 }
 
-// decodeResponse decodes server JSON responses into resp. It returns an error if the
-// decoding process fails.
-func (c Client) decodeResponse(resp interface{}) error {
-	return c.Decoder.Decode(&resp)
+// decodeResponse reads server envelopes until it finds the response to the request
+// identified by requestID and decodes its payload into resp. Unsolicited messages and
+// responses to other requests are skipped. Error envelopes are returned as errors.
+func (c *Client) decodeResponse(requestID string, resp messages.Response) error {
+	for {
+		var envelope messages.Envelope
+		err := c.Decoder.Decode(&envelope)
+		if err != nil {
+			return err
+		}
+
+		if envelope.RequestID != "" && envelope.RequestID != requestID {
+			continue
+		}
+
+		if envelope.Type == messages.ErrorType {
+			var respErr messages.Error
+			err = envelope.Decode(&respErr)
+			if err != nil {
+				return err
+			}
+
+			return &respErr
+		}
+
+		if envelope.Type != resp.MessageType() {
+			continue
+		}
+
+		return envelope.Decode(resp)
+	}
 }
 
 // getUserName prompts players to enter their user name. It returns the user name.
 // Empty values are not valid user names.
-func (c Client) getUserName() string {
+func (c *Client) getUserName() string {
 	var username string
 	for {
 		fmt.Fprint(c.Output, "=> Enter your username: ")
@@ -122,11 +243,88 @@ func (c Client) getUserName() string {
 	return username
 }
 
+// handshake sends the client protocol version to the server and stores the server
+// capabilities. Servers that do not support the handshake reply with an unknown
+// action error; in that case the client carries on without capabilities.
+func (c *Client) handshake() error {
+	id, err := c.encodeRequest(messages.PlayerReq{
+		Action:  game.Handshake,
+		Version: messages.ProtocolVersion,
+	})
+	if err != nil {
+		return err
+	}
+
+	var resp messages.HandshakeResp
+	err = c.decodeResponse(id, &resp)
+	if err != nil {
+		if respErr, ok := err.(*messages.Error); ok && respErr.Code == messages.UnknownAction {
+			return nil
+		}
+
+		return err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+	c.Capabilities = &resp
+
+	return nil
+}
+
+// keepAliveInterval returns how often the client pings the server, half of the idle
+// timeout advertised in the handshake, or 0 if the server does not time out idle
+// sessions or does not support ping.
+func (c *Client) keepAliveInterval() time.Duration {
+	if c.Capabilities == nil || !c.supports(game.Ping) {
+		return 0
+	}
+
+	return time.Duration(c.Capabilities.Limits.IdleTimeoutSeconds) * time.Second / 2
+}
+
+// keepAlive pings the server every interval, so that players taking their time to
+// think about their next move are not disconnected. The pongs are skipped by the
+// following decodeResponse calls. It returns once a ping cannot be sent.
+func (c *Client) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		_, err := c.encodeRequest(messages.PlayerReq{Action: game.Ping})
+		if err != nil {
+			return
+		}
+	}
+}
+
+// supports returns true if the server advertised action during the handshake.
+// All actions are assumed to be supported if the handshake did not take place.
+func (c *Client) supports(action game.PlayerAction) bool {
+	if c.Capabilities == nil {
+		return true
+	}
+
+	return c.Capabilities.Supports(action)
+}
+
 // authenticateUser sends a login request including the username to the upstream server.
-func (c Client) authenticateUser() error {
+// If the client holds a session token it tries to reconnect to that session first.
+func (c *Client) authenticateUser() error {
+	if c.Token != "" && c.supports(game.Reconnect) {
+		err := c.reconnect()
+		if err == nil {
+			return nil
+		}
+
+		fmt.Fprintf(c.Output, "Could not resume your session: %v \n", err)
+		c.Token = ""
+	}
+
 	username := c.getUserName()
 
-	err := c.encodeRequest(messages.PlayerReq{
+	id, err := c.encodeRequest(messages.PlayerReq{
 		Action: game.Login,
 		Value:  username,
 	})
@@ -135,7 +333,7 @@ func (c Client) authenticateUser() error {
 	}
 
 	var resp messages.HelpResp
-	err = c.decodeResponse(&resp)
+	err = c.decodeResponse(id, &resp)
 	if err != nil {
 		return err
 	}
@@ -145,18 +343,52 @@ func (c Client) authenticateUser() error {
 	}
 	fmt.Fprintln(c.Output, resp.Info)
 
+	if resp.Token != "" {
+		c.Token = resp.Token
+		fmt.Fprintf(c.Output, "Your session token is %s. Start the client with '--token %s' to get back to your game after a disconnection \n", resp.Token, resp.Token)
+	}
+
+	return nil
+}
+
+// reconnect resumes the session identified by the client token and displays the
+// game that was active when the previous connection was lost.
+func (c *Client) reconnect() error {
+	id, err := c.encodeRequest(messages.PlayerReq{
+		Action: game.Reconnect,
+		Value:  c.Token,
+	})
+	if err != nil {
+		return err
+	}
+
+	var resp messages.SessionResp
+	err = c.decodeResponse(id, &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	fmt.Fprintf(c.Output, "Welcome back %s \n", resp.UserID)
+	if resp.Game != nil {
+		drawing.State(c.Output, *resp.Game)
+	}
+
 	return nil
 }
 
 // newGameRequest sends a new game request to the server and displays the response.
-func (c Client) newGameRequest() {
-	err := c.Encoder.Encode(messages.PlayerReq{Action: game.NewGame})
+func (c *Client) newGameRequest(difficulty string) {
+	id, err := c.encodeRequest(messages.PlayerReq{Action: game.NewGame, Value: difficulty})
 	if err != nil {
 		fmt.Fprintf(c.Output, "unexpected error: %v \n", err)
 	}
 
 	var resp messages.GameStateResp
-	err = c.decodeResponse(&resp)
+	err = c.decodeResponse(id, &resp)
 	if err != nil {
 		fmt.Fprintf(c.Output, "unexpected error: %s \n", err)
 	}
@@ -164,37 +396,54 @@ func (c Client) newGameRequest() {
 	if resp.Error != nil {
 		fmt.Fprintln(c.Output, resp.Error)
 	} else {
-		fmt.Fprintf(c.Output, "Guess the hero: %s \n", resp.State.WordToGuess)
-		fmt.Fprintln(c.Output, drawing.Display[len(resp.State.CharsTried)])
-		fmt.Fprintf(c.Output, "Characters tried: %s \n", strings.Join(resp.State.CharsTried, " - "))
+		drawing.State(c.Output, resp.State)
 	}
 }
 
 // helpRequest sends a help request to the server and displays the response.
-func (c Client) helpRequest() {
-	err := c.encodeRequest(messages.PlayerReq{Action: game.Help})
+func (c *Client) helpRequest() {
+	id, err := c.encodeRequest(messages.PlayerReq{Action: game.Help})
 	if err != nil {
 		fmt.Fprintf(c.Output, "unexpected error: %v \n", err)
 	}
 
 	var resp messages.HelpResp
-	err = c.decodeResponse(&resp)
+	err = c.decodeResponse(id, &resp)
 	if err != nil {
 		fmt.Printf("Unexpected error: %v", err)
 	}
 
 	fmt.Fprintln(c.Output, resp.Info)
+	drawing.Categories(c.Output, resp.Categories)
+}
+
+// pingRequest sends a ping request to the server and displays the response.
+func (c *Client) pingRequest() {
+	id, err := c.encodeRequest(messages.PlayerReq{Action: game.Ping})
+	if err != nil {
+		fmt.Fprintf(c.Output, "unexpected error: %v \n", err)
+		return
+	}
+
+	var resp messages.PongResp
+	err = c.decodeResponse(id, &resp)
+	if err != nil {
+		fmt.Fprintf(c.Output, "unexpected error: %v \n", err)
+		return
+	}
+
+	fmt.Fprintln(c.Output, "pong")
 }
 
 // listGamesRequest sends a list games request to the server and displays the response.
-func (c Client) listGamesRequest() {
-	err := c.encodeRequest(messages.PlayerReq{Action: game.ListGames})
+func (c *Client) listGamesRequest() {
+	id, err := c.encodeRequest(messages.PlayerReq{Action: game.ListGames})
 	if err != nil {
 		fmt.Fprintf(c.Output, "unexpected error: %v \n", err)
 	}
 
 	var resp messages.ListGamesResp
-	err = c.decodeResponse(&resp)
+	err = c.decodeResponse(id, &resp)
 	if err != nil {
 		fmt.Fprintf(c.Output, "unexpected error: %v", err)
 	}
@@ -204,25 +453,19 @@ func (c Client) listGamesRequest() {
 		return
 	}
 
-	if len(resp.Games) == 0 {
-		fmt.Fprintf(c.Output, "no games have been found. Type '%v' to start \n", game.NewGame)
-	} else {
-		for _, g := range resp.Games {
-			fmt.Fprintf(c.Output, "Game ID: %d * Hero: %s * Characters tried: %v * Status: %v \n", g.GameID, g.WordToGuess, g.CharsTried, g.Status)
-		}
-	}
+	drawing.Games(c.Output, resp.Games)
 }
 
 // resumeGameRequest sends a resume games request to the server and displays the response.
 // The request must contain the id of the game to resume.
-func (c Client) resumeGameRequest(gameID string) {
-	err := c.encodeRequest(messages.PlayerReq{Action: game.ResumeGame, Value: gameID})
+func (c *Client) resumeGameRequest(gameID string) {
+	id, err := c.encodeRequest(messages.PlayerReq{Action: game.ResumeGame, Value: gameID})
 	if err != nil {
 		fmt.Fprintf(c.Output, "unexpected error: %v \n", err)
 	}
 
 	var resp messages.GameStateResp
-	err = c.decodeResponse(&resp)
+	err = c.decodeResponse(id, &resp)
 	if err != nil {
 		fmt.Fprintf(c.Output, "Unexpected error: %v", err)
 	}
@@ -232,20 +475,18 @@ func (c Client) resumeGameRequest(gameID string) {
 		return
 	}
 
-	fmt.Fprintf(c.Output, "Guess the hero: %s \n", resp.State.WordToGuess)
-	fmt.Fprintln(c.Output, drawing.Display[len(resp.State.CharsTried)])
-	fmt.Fprintf(c.Output, "Characters tried: %s \n", strings.Join(resp.State.CharsTried, " - "))
+	drawing.State(c.Output, resp.State)
 }
 
 // guessRequest sends a guess request to the server and displays the response.
 // The request must contain the value to try against the hidden word.
-func (c Client) guessRequest(guess string) {
-	err := c.encodeRequest(messages.PlayerReq{Action: game.Guess, Value: guess})
+func (c *Client) guessRequest(guess string) {
+	id, err := c.encodeRequest(messages.PlayerReq{Action: game.Guess, Value: guess})
 	if err != nil {
 		fmt.Fprintf(c.Output, "unexpected error: %v \n", err)
 	}
 	var resp messages.GameStateResp
-	err = c.decodeResponse(&resp)
+	err = c.decodeResponse(id, &resp)
 	if err != nil {
 		fmt.Fprintf(c.Output, "Unexpected error: %v", err)
 	}
@@ -253,30 +494,70 @@ func (c Client) guessRequest(guess string) {
 	if resp.Error != nil {
 		fmt.Fprintf(c.Output, "Error: %s \n", resp.Error.Message)
 	} else {
-		fmt.Fprintf(c.Output, "Guess the hero: %s \n", resp.State.WordToGuess)
-		fmt.Fprintln(c.Output, drawing.Display[len(resp.State.CharsTried)])
-		fmt.Fprintf(c.Output, "Characters tried: %s \n", strings.Join(resp.State.CharsTried, " - "))
+		drawing.State(c.Output, resp.State)
+		drawing.Outcome(c.Output, resp.Outcome)
+	}
+}
 
-		if resp.State.Status == game.GameOver {
-			fmt.Fprintln(c.Output, "*** GAME OVER ***")
-		}
+// solveRequest sends a solve request to the server and displays the response.
+// The request must contain the word to try as the hidden word.
+func (c *Client) solveRequest(word string) {
+	id, err := c.encodeRequest(messages.PlayerReq{Action: game.Solve, Value: word})
+	if err != nil {
+		fmt.Fprintf(c.Output, "unexpected error: %v \n", err)
+	}
+	var resp messages.GameStateResp
+	err = c.decodeResponse(id, &resp)
+	if err != nil {
+		fmt.Fprintf(c.Output, "Unexpected error: %v", err)
+	}
 
-		if resp.State.Status == game.Won {
-			fmt.Fprintln(c.Output, "*** YOU WIN ***")
-		}
+	if resp.Error != nil {
+		fmt.Fprintf(c.Output, "Error: %s \n", resp.Error.Message)
+	} else {
+		drawing.State(c.Output, resp.State)
+		drawing.Outcome(c.Output, resp.Outcome)
+	}
+}
+
+// hintRequest sends a hint request to the server and displays the letter revealed.
+func (c *Client) hintRequest() {
+	id, err := c.encodeRequest(messages.PlayerReq{Action: game.Hint})
+	if err != nil {
+		fmt.Fprintf(c.Output, "unexpected error: %v \n", err)
+	}
+	var resp messages.GameStateResp
+	err = c.decodeResponse(id, &resp)
+	if err != nil {
+		fmt.Fprintf(c.Output, "Unexpected error: %v", err)
+	}
+
+	if resp.Error != nil {
+		fmt.Fprintf(c.Output, "Error: %s \n", resp.Error.Message)
+	} else {
+		drawing.State(c.Output, resp.State)
+		drawing.Hint(c.Output, resp.State, resp.Hint)
 	}
 }
 
 // handleUserCommands takes the command issued by the player inteh form of a request
 // message and calls the approprioate action to perform according to the command type.
-func (c Client) handleUserCommands(req messages.PlayerReq) {
+func (c *Client) handleUserCommands(req messages.PlayerReq) {
+	if !c.supports(req.Action) {
+		fmt.Fprintf(c.Output, "Command '%v' is not supported by the server. Type '%v' to see the available actions \n", req.Action, game.Help)
+		return
+	}
+
 	switch req.Action {
 	case game.NewGame:
-		c.newGameRequest()
+		c.newGameRequest(req.Value)
 
 	case game.Help:
 		c.helpRequest()
 
+	case game.Ping:
+		c.pingRequest()
+
 	case game.ListGames:
 		c.listGamesRequest()
 
@@ -286,6 +567,12 @@ func (c Client) handleUserCommands(req messages.PlayerReq) {
 	case game.Guess:
 		c.guessRequest(req.Value)
 
+	case game.Solve:
+		c.solveRequest(req.Value)
+
+	case game.Hint:
+		c.hintRequest()
+
 	default:
 		fmt.Fprintf(c.Output, "Unknown command. Type '%v' to see the available actions \n", game.Help)
 	}
@@ -293,7 +580,7 @@ func (c Client) handleUserCommands(req messages.PlayerReq) {
 
 // handleGameIO listens to the players input commands and sends them off for porcessing
 // to the upstream server.
-func (c Client) handleGameIO() error {
+func (c *Client) handleGameIO() error {
 	for {
 		req := c.parseUserCommand()
 		c.handleUserCommands(req)
@@ -3,17 +3,38 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"io/ioutil"
+	"log"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/Popcore/hangmango/pkg/client/drawing"
 	"github.com/Popcore/hangmango/pkg/game"
 	"github.com/Popcore/hangmango/pkg/messages"
+	"github.com/Popcore/hangmango/pkg/server/handlers"
+	"github.com/Popcore/hangmango/pkg/store"
+	"github.com/Popcore/hangmango/pkg/websocket"
 )
 
+// sendResponse wraps resp in an unsolicited envelope and writes it to conn.
+func sendResponse(conn io.Writer, resp messages.Response) error {
+	envelope, err := messages.NewEnvelope("", resp)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(conn).Encode(envelope)
+}
+
 func TestNewGameRequest(t *testing.T) {
 
 	// wConn and rConn are out mocked net.Conn and allow write and read operations
@@ -32,7 +53,7 @@ func TestNewGameRequest(t *testing.T) {
 	}
 
 	go func() {
-		json.NewEncoder(wConn).Encode(resp)
+		sendResponse(wConn, resp)
 	}()
 
 	var buf bytes.Buffer
@@ -60,7 +81,7 @@ func TestNewGameRequestError(t *testing.T) {
 	}
 
 	go func() {
-		json.NewEncoder(wConn).Encode(resp)
+		sendResponse(wConn, resp)
 	}()
 
 	var buf bytes.Buffer
@@ -86,7 +107,7 @@ func TestHelpRequest(t *testing.T) {
 	}
 
 	go func() {
-		json.NewEncoder(wConn).Encode(resp)
+		sendResponse(wConn, resp)
 	}()
 
 	var buf bytes.Buffer
@@ -119,13 +140,15 @@ func TestListGamesRequest(t *testing.T) {
 				WordToGuess: "bar",
 				CharsTried:  []string{"c", "d"},
 				Status:      game.Won,
+				Difficulty:  game.Hard,
+				Category:    "villains",
 			},
 		},
 		Error: nil,
 	}
 
 	go func() {
-		json.NewEncoder(wConn).Encode(resp)
+		sendResponse(wConn, resp)
 	}()
 
 	var buf bytes.Buffer
@@ -138,7 +161,7 @@ func TestListGamesRequest(t *testing.T) {
 	client.handleUserCommands(messages.PlayerReq{Action: game.ListGames})
 
 	assert.Contains(t, buf.String(), "Game ID: 1 * Hero: _ _ _  * Characters tried: [a b] * Status: paused")
-	assert.Contains(t, buf.String(), "Game ID: 2 * Hero: _ _ _  * Characters tried: [c d] * Status: won")
+	assert.Contains(t, buf.String(), "Game ID: 2 * Word (villains): _ _ _  * Characters tried: [c d] * Status: won * Difficulty: hard")
 }
 
 func TestListGamesRequestErr(t *testing.T) {
@@ -154,7 +177,7 @@ func TestListGamesRequestErr(t *testing.T) {
 	}
 
 	go func() {
-		json.NewEncoder(wConn).Encode(resp)
+		sendResponse(wConn, resp)
 	}()
 
 	var buf bytes.Buffer
@@ -185,7 +208,7 @@ func TestResumeGamesRequest(t *testing.T) {
 	}
 
 	go func() {
-		json.NewEncoder(wConn).Encode(resp)
+		sendResponse(wConn, resp)
 	}()
 
 	var buf bytes.Buffer
@@ -202,20 +225,50 @@ func TestResumeGamesRequest(t *testing.T) {
 	assert.Contains(t, buf.String(), "Characters tried: a")
 }
 
+func TestResumeGamesRequestDifficulty(t *testing.T) {
+	wConn, rConn := net.Pipe()
+	defer wConn.Close()
+	defer rConn.Close()
+
+	resp := messages.GameStateResp{
+		State: game.State{
+			GameID:      1,
+			WordToGuess: "foo",
+			CharsTried:  []string{"a", "b"},
+			Status:      game.InProgress,
+			Difficulty:  game.Difficulty{Level: game.LevelCustom, MaxMisses: 2},
+		},
+	}
+
+	go func() {
+		sendResponse(wConn, resp)
+	}()
+
+	var buf bytes.Buffer
+	client := Client{
+		Output:  &buf,
+		Encoder: json.NewEncoder(ioutil.Discard),
+		Decoder: json.NewDecoder(rConn),
+	}
+
+	client.handleUserCommands(messages.PlayerReq{Action: game.ResumeGame, Value: "1"})
+
+	assert.Contains(t, buf.String(), drawing.Display[7])
+}
+
 func TestResumeGamesRequestErr(t *testing.T) {
 	wConn, rConn := net.Pipe()
 	defer wConn.Close()
 	defer rConn.Close()
 
-	resp := messages.ListGamesResp{
-		Games: []game.State{},
+	resp := messages.GameStateResp{
 		Error: &messages.Error{
 			Message: "the error message",
 		},
 	}
 
 	go func() {
-		json.NewEncoder(wConn).Encode(resp)
+		sendResponse(wConn, resp)
 	}()
 
 	var buf bytes.Buffer
@@ -246,7 +299,7 @@ func TestGuessRequestRequest(t *testing.T) {
 	}
 
 	go func() {
-		json.NewEncoder(wConn).Encode(resp)
+		sendResponse(wConn, resp)
 	}()
 
 	var buf bytes.Buffer
@@ -261,6 +314,108 @@ func TestGuessRequestRequest(t *testing.T) {
 	assert.Contains(t, buf.String(), "Guess the hero: _ _ _ ")
 	assert.Contains(t, buf.String(), drawing.Display[2])
 	assert.Contains(t, buf.String(), "Characters tried: a - b")
+	assert.NotContains(t, buf.String(), "already tried")
+}
+
+func TestGuessRequestRepeat(t *testing.T) {
+	wConn, rConn := net.Pipe()
+	defer wConn.Close()
+	defer rConn.Close()
+
+	resp := messages.GameStateResp{
+		State: game.State{
+			GameID:       1,
+			WordToGuess:  "foo",
+			CharsGuessed: []string{"o"},
+			CharsTried:   []string{},
+			Status:       game.InProgress,
+		},
+		Outcome: game.OutcomeRepeat,
+	}
+
+	go func() {
+		sendResponse(wConn, resp)
+	}()
+
+	var buf bytes.Buffer
+	client := Client{
+		Output:  &buf,
+		Encoder: json.NewEncoder(ioutil.Discard),
+		Decoder: json.NewDecoder(rConn),
+	}
+
+	client.handleUserCommands(messages.PlayerReq{Action: game.Guess, Value: "o"})
+
+	assert.Contains(t, buf.String(), "Guess the hero: _ o o ")
+	assert.Contains(t, buf.String(), "You have already tried that")
+}
+
+func TestSolveRequest(t *testing.T) {
+	wConn, rConn := net.Pipe()
+	defer wConn.Close()
+	defer rConn.Close()
+
+	resp := messages.GameStateResp{
+		State: game.State{
+			GameID:      1,
+			WordToGuess: "foo",
+			CharsTried:  []string{"a"},
+			WordsTried:  []string{"bar"},
+			Status:      game.InProgress,
+		},
+		Outcome: game.OutcomeMiss,
+	}
+
+	go func() {
+		sendResponse(wConn, resp)
+	}()
+
+	var buf bytes.Buffer
+	client := Client{
+		Output:  &buf,
+		Encoder: json.NewEncoder(ioutil.Discard),
+		Decoder: json.NewDecoder(rConn),
+	}
+
+	client.handleUserCommands(messages.PlayerReq{Action: game.Solve, Value: "bar"})
+
+	assert.Contains(t, buf.String(), drawing.Display[3])
+	assert.Contains(t, buf.String(), "Words tried: bar")
+}
+
+func TestHintRequest(t *testing.T) {
+	wConn, rConn := net.Pipe()
+	defer wConn.Close()
+	defer rConn.Close()
+
+	resp := messages.GameStateResp{
+		State: game.State{
+			GameID:       1,
+			WordToGuess:  "f _ _",
+			CharsGuessed: []string{"f"},
+			HintsUsed:    1,
+			Status:       game.InProgress,
+			Difficulty:   game.Easy,
+		},
+		Outcome: game.OutcomeHint,
+		Hint:    "f",
+	}
+
+	go func() {
+		sendResponse(wConn, resp)
+	}()
+
+	var buf bytes.Buffer
+	client := Client{
+		Output:  &buf,
+		Encoder: json.NewEncoder(ioutil.Discard),
+		Decoder: json.NewDecoder(rConn),
+	}
+
+	client.handleUserCommands(messages.PlayerReq{Action: game.Hint})
+
+	assert.Contains(t, buf.String(), "Hint: the hero contains the letter f")
+	assert.Contains(t, buf.String(), "Hints left: 2")
 }
 
 func TestGuessRequestErr(t *testing.T) {
@@ -268,15 +423,14 @@ func TestGuessRequestErr(t *testing.T) {
 	defer wConn.Close()
 	defer rConn.Close()
 
-	resp := messages.ListGamesResp{
-		Games: []game.State{},
+	resp := messages.GameStateResp{
 		Error: &messages.Error{
 			Message: "the error message",
 		},
 	}
 
 	go func() {
-		json.NewEncoder(wConn).Encode(resp)
+		sendResponse(wConn, resp)
 	}()
 
 	var buf bytes.Buffer
@@ -290,3 +444,324 @@ func TestGuessRequestErr(t *testing.T) {
 
 	assert.Contains(t, buf.String(), "the error message")
 }
+
+func TestDecodeResponse(t *testing.T) {
+	wConn, rConn := net.Pipe()
+	defer wConn.Close()
+	defer rConn.Close()
+
+	go func() {
+		encoder := json.NewEncoder(wConn)
+
+		other, _ := messages.NewEnvelope("other-id", messages.HelpResp{Info: "not for us"})
+		encoder.Encode(other)
+
+		unsolicited, _ := messages.NewEnvelope("", messages.ListGamesResp{})
+		encoder.Encode(unsolicited)
+
+		expected, _ := messages.NewEnvelope("1", messages.HelpResp{Info: "the info message"})
+		encoder.Encode(expected)
+
+		failed, _ := messages.NewEnvelope("2", &messages.Error{Message: "the error message"})
+		encoder.Encode(failed)
+	}()
+
+	client := Client{
+		Decoder: json.NewDecoder(rConn),
+	}
+
+	var resp messages.HelpResp
+	err := client.decodeResponse("1", &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, "the info message", resp.Info)
+
+	err = client.decodeResponse("2", &resp)
+	assert.EqualError(t, err, "the error message")
+}
+
+func TestHandshake(t *testing.T) {
+	wConn, rConn := net.Pipe()
+	defer wConn.Close()
+	defer rConn.Close()
+
+	resp := messages.HandshakeResp{
+		Version: messages.ProtocolVersion,
+		Actions: []game.PlayerAction{game.Handshake, game.Login, game.Help},
+		Modes:   []string{"classic"},
+		Limits:  messages.Limits{MaxWrongChars: 7},
+	}
+
+	go func() {
+		sendResponse(wConn, resp)
+	}()
+
+	var buf bytes.Buffer
+	client := Client{
+		Output:  &buf,
+		Encoder: json.NewEncoder(ioutil.Discard),
+		Decoder: json.NewDecoder(rConn),
+	}
+
+	err := client.handshake()
+	assert.Nil(t, err)
+	assert.Equal(t, &resp, client.Capabilities)
+
+	client.handleUserCommands(messages.PlayerReq{Action: game.ListGames})
+	assert.Contains(t, buf.String(), "Command 'list' is not supported by the server")
+}
+
+func TestHandshakeUnsupported(t *testing.T) {
+	wConn, rConn := net.Pipe()
+	defer wConn.Close()
+	defer rConn.Close()
+
+	go func() {
+		sendResponse(wConn, messages.NewError(messages.UnknownAction, "action hello was not recognized"))
+	}()
+
+	client := Client{
+		Encoder: json.NewEncoder(ioutil.Discard),
+		Decoder: json.NewDecoder(rConn),
+	}
+
+	err := client.handshake()
+	assert.Nil(t, err)
+	assert.Nil(t, client.Capabilities)
+	assert.True(t, client.supports(game.ListGames))
+}
+
+func TestHandshakeUnsupportedVersion(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	system := handlers.System{
+		Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:  store.NewMemStore(),
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handlers.NewSession(system, serverConn)
+		serverConn.Close()
+	}()
+
+	client := Client{
+		Encoder: json.NewEncoder(clientConn),
+		Decoder: json.NewDecoder(clientConn),
+	}
+
+	id, err := client.encodeRequest(messages.PlayerReq{Action: game.Handshake, Version: messages.MinProtocolVersion - 1})
+	assert.Nil(t, err)
+
+	var resp messages.HandshakeResp
+	err = client.decodeResponse(id, &resp)
+	assert.Nil(t, err)
+	if assert.NotNil(t, resp.Error) {
+		assert.Equal(t, messages.UnsupportedVersion, resp.Error.Code)
+	}
+
+	// the client can still negotiate a supported version
+	err = client.handshake()
+	assert.Nil(t, err)
+	if assert.NotNil(t, client.Capabilities) {
+		assert.Equal(t, messages.ProtocolVersion, client.Capabilities.Version)
+	}
+
+	clientConn.Close()
+	assert.Equal(t, io.EOF, <-done)
+}
+
+func TestKeepAlive(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer ln.Close()
+
+	system := handlers.System{
+		Logger:      log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:       store.NewMemStore(),
+		IdleTimeout: time.Second,
+	}
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		handlers.NewSession(system, conn)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	var buf bytes.Buffer
+	client := Client{
+		Output:  &buf,
+		Encoder: json.NewEncoder(conn),
+		Decoder: json.NewDecoder(conn),
+	}
+
+	assert.Nil(t, client.handshake())
+	assert.Equal(t, 500*time.Millisecond, client.keepAliveInterval())
+
+	go client.keepAlive(client.keepAliveInterval())
+
+	// the player stays idle longer than the idle timeout
+	time.Sleep(3 * system.IdleTimeout / 2)
+
+	client.handleUserCommands(messages.PlayerReq{Action: game.Help})
+	assert.Contains(t, buf.String(), game.Rules)
+}
+
+func TestKeepAliveInterval(t *testing.T) {
+	client := Client{}
+	assert.Zero(t, client.keepAliveInterval())
+
+	client.Capabilities = &messages.HandshakeResp{Actions: []game.PlayerAction{game.Ping}}
+	assert.Zero(t, client.keepAliveInterval())
+
+	client.Capabilities.Limits.IdleTimeoutSeconds = 600
+	assert.Equal(t, 5*time.Minute, client.keepAliveInterval())
+
+	client.Capabilities.Actions = []game.PlayerAction{game.Help}
+	assert.Zero(t, client.keepAliveInterval())
+}
+
+func TestNewWithWebSocket(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		var req messages.PlayerReq
+		json.NewDecoder(conn).Decode(&req)
+
+		envelope, _ := messages.NewEnvelope(req.ID, messages.HelpResp{Info: "the info message"})
+		json.NewEncoder(conn).Encode(envelope)
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	assert.Nil(t, err)
+
+	client, err := New(port, WithWebSocket())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var buf bytes.Buffer
+	client.Output = &buf
+	client.handleUserCommands(messages.PlayerReq{Action: game.Help})
+
+	assert.Contains(t, buf.String(), "the info message")
+}
+
+func TestNewWithUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "client")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hangmango.sock")
+	l, err := net.Listen("unix", path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var req messages.PlayerReq
+		json.NewDecoder(conn).Decode(&req)
+
+		envelope, _ := messages.NewEnvelope(req.ID, messages.HelpResp{Info: "the info message"})
+		json.NewEncoder(conn).Encode(envelope)
+	}()
+
+	_, err = New("unix://"+path, WithWebSocket())
+	assert.Equal(t, ErrUnixTransport, err)
+
+	client, err := New("unix://" + path)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var buf bytes.Buffer
+	client.Output = &buf
+	client.handleUserCommands(messages.PlayerReq{Action: game.Help})
+
+	assert.Contains(t, buf.String(), "the info message")
+}
+
+func TestPingRequest(t *testing.T) {
+	wConn, rConn := net.Pipe()
+	defer wConn.Close()
+	defer rConn.Close()
+
+	go func() {
+		sendResponse(wConn, messages.PongResp{})
+	}()
+
+	var buf bytes.Buffer
+	client := Client{
+		Output:  &buf,
+		Encoder: json.NewEncoder(ioutil.Discard),
+		Decoder: json.NewDecoder(rConn),
+	}
+
+	client.handleUserCommands(messages.PlayerReq{Action: game.Ping})
+
+	assert.Equal(t, "pong\n", buf.String())
+}
+
+func TestReconnect(t *testing.T) {
+	wConn, rConn := net.Pipe()
+	defer wConn.Close()
+	defer rConn.Close()
+
+	go func() {
+		sendResponse(wConn, messages.SessionResp{
+			UserID: "user-id",
+			Token:  "the-token",
+			Game: &game.State{
+				GameID:      1,
+				WordToGuess: "foo",
+				CharsTried:  []string{"a"},
+				Status:      game.InProgress,
+			},
+		})
+		sendResponse(wConn, messages.SessionResp{
+			Error: messages.NewError(messages.InvalidToken, "the error message"),
+		})
+	}()
+
+	var buf bytes.Buffer
+	client := Client{
+		Output:  &buf,
+		Encoder: json.NewEncoder(ioutil.Discard),
+		Decoder: json.NewDecoder(rConn),
+		Token:   "the-token",
+	}
+
+	assert.Nil(t, client.reconnect())
+	assert.Contains(t, buf.String(), "Welcome back user-id")
+	assert.Contains(t, buf.String(), "Guess the hero: _ _ _ ")
+	assert.Contains(t, buf.String(), "Characters tried: a")
+
+	err := client.reconnect()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "the error message", err.Error())
+	}
+}
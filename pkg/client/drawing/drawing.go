@@ -74,3 +74,20 @@ _|___
 `,
 	}
 )
+
+// Frame returns the gallows frame to display after misses wrong characters in a game
+// that ends after maxMisses of them. The misses before the limit are spread over all
+// the frames but the last one, which is only displayed when the game is lost.
+func Frame(misses, maxMisses int) string {
+	if misses <= 0 || maxMisses <= 0 {
+		return Display[0]
+	}
+
+	if misses >= maxMisses {
+		return Display[len(Display)]
+	}
+
+	frames := len(Display) - 1
+
+	return Display[(misses*frames+maxMisses-2)/(maxMisses-1)]
+}
@@ -0,0 +1,26 @@
+package drawing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrame(t *testing.T) {
+	testcases := []struct {
+		maxMisses int
+		frames    []int
+	}{
+		{maxMisses: 5, frames: []int{0, 2, 3, 5, 6, 7}},
+		{maxMisses: 7, frames: []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{maxMisses: 10, frames: []int{0, 1, 2, 2, 3, 4, 4, 5, 6, 6, 7}},
+	}
+
+	for _, tc := range testcases {
+		for misses, frame := range tc.frames {
+			assert.Equal(t, Display[frame], Frame(misses, tc.maxMisses), "%d misses out of %d", misses, tc.maxMisses)
+		}
+
+		assert.Equal(t, Display[7], Frame(tc.maxMisses+1, tc.maxMisses))
+	}
+}
@@ -0,0 +1,90 @@
+package drawing
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Popcore/hangmango/pkg/game"
+	"github.com/Popcore/hangmango/pkg/words"
+)
+
+// State writes the word to guess, the gallows and the characters tried of a game
+// to w. The gallows frame depends on the miss limit of the game difficulty.
+// Finished games are followed by the game outcome.
+func State(w io.Writer, state game.State) {
+	fmt.Fprintf(w, "Guess the %s: %s \n", subject(state), state.WordToGuess)
+	if state.Clue != "" {
+		fmt.Fprintf(w, "Clue: %s \n", state.Clue)
+	}
+	fmt.Fprintln(w, Frame(state.Misses(), state.MaxMisses()))
+	fmt.Fprintf(w, "Characters tried: %s \n", strings.Join(state.CharsTried, " - "))
+	if len(state.WordsTried) > 0 {
+		fmt.Fprintf(w, "Words tried: %s \n", strings.Join(state.WordsTried, " - "))
+	}
+	if state.Status == game.InProgress && state.HintsLeft() > 0 {
+		fmt.Fprintf(w, "Hints left: %d \n", state.HintsLeft())
+	}
+
+	switch state.Status {
+	case game.GameOver:
+		fmt.Fprintln(w, "*** GAME OVER ***")
+
+	case game.Won:
+		fmt.Fprintln(w, "*** YOU WIN ***")
+	}
+}
+
+// Outcome writes to w the outcome of a guess that the game state alone does not show.
+func Outcome(w io.Writer, outcome game.Outcome) {
+	if outcome == game.OutcomeRepeat {
+		fmt.Fprintln(w, "You have already tried that")
+	}
+}
+
+// Hint writes to w the letter revealed by a hint in the word to guess of state.
+func Hint(w io.Writer, state game.State, letter string) {
+	if letter != "" {
+		fmt.Fprintf(w, "Hint: the %s contains the letter %s \n", subject(state), letter)
+	}
+}
+
+// Categories writes to w the categories of words a player can choose from.
+func Categories(w io.Writer, categories []string) {
+	if len(categories) > 0 {
+		fmt.Fprintf(w, "Categories: %s. Type '%v <category>' to play one \n", strings.Join(categories, ", "), game.NewGame)
+	}
+}
+
+// subject describes what the word to guess of state is.
+func subject(state game.State) string {
+	if state.Category == "" || state.Category == words.DefaultCategory {
+		return "hero"
+	}
+
+	return fmt.Sprintf("word (%s)", state.Category)
+}
+
+// title returns s with its first letter in upper case.
+func title(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// Games writes a summary line for each game to w.
+func Games(w io.Writer, games []game.State) {
+	if len(games) == 0 {
+		fmt.Fprintf(w, "no games have been found. Type '%v' to start \n", game.NewGame)
+		return
+	}
+
+	for _, g := range games {
+		fmt.Fprintf(w, "Game ID: %d * %s: %s * Characters tried: %v * Status: %v", g.GameID, title(subject(g)), g.WordToGuess, g.CharsTried, g.Status)
+		if len(g.WordsTried) > 0 {
+			fmt.Fprintf(w, " * Words tried: %v", g.WordsTried)
+		}
+		if g.Difficulty.Level != "" {
+			fmt.Fprintf(w, " * Difficulty: %s", g.Difficulty.Level)
+		}
+		fmt.Fprint(w, " \n")
+	}
+}
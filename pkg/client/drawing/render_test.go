@@ -0,0 +1,25 @@
+package drawing
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Popcore/hangmango/pkg/game"
+)
+
+func TestHint(t *testing.T) {
+	var buf bytes.Buffer
+
+	Hint(&buf, game.State{}, "f")
+	assert.Equal(t, "Hint: the hero contains the letter f \n", buf.String())
+
+	buf.Reset()
+	Hint(&buf, game.State{Category: "fromages"}, "f")
+	assert.Equal(t, "Hint: the word (fromages) contains the letter f \n", buf.String())
+
+	buf.Reset()
+	Hint(&buf, game.State{Category: "fromages"}, "")
+	assert.Empty(t, buf.String())
+}
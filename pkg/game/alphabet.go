@@ -0,0 +1,130 @@
+package game
+
+import (
+	"strings"
+	"unicode"
+)
+
+// separators are the characters of a phrase that are not guessed but shown from the
+// start of the game, e.g. the space and the hyphen of "spider-man and robin".
+const separators = " -'’"
+
+// folds maps the accented letters of the supported alphabets to their base letter.
+// Letters such as ñ and ß, that are distinct letters rather than accented ones,
+// are not folded.
+var folds = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ä': 'a',
+	'ç': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'ö': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ý': 'y', 'ÿ': 'y',
+}
+
+// Alphabet describes the letters a player can guess in a game. If FoldAccents is
+// set accented letters are folded to their base letter, so that guessing "e" reveals
+// both the e and the é of the word. The zero Alphabet accepts any letter and does
+// not fold accents.
+type Alphabet struct {
+	Name        string `json:"name"`
+	Letters     string `json:"letters"`
+	FoldAccents bool   `json:"fold_accents,omitempty"`
+}
+
+var (
+	English = Alphabet{Name: "en", Letters: "abcdefghijklmnopqrstuvwxyz"}
+	French  = Alphabet{Name: "fr", Letters: "abcdefghijklmnopqrstuvwxyzàâæçéèêëîïôœùûüÿ", FoldAccents: true}
+	German  = Alphabet{Name: "de", Letters: "abcdefghijklmnopqrstuvwxyzäöüß", FoldAccents: true}
+	Spanish = Alphabet{Name: "es", Letters: "abcdefghijklmnopqrstuvwxyzáéíñóúü", FoldAccents: true}
+
+	// Alphabets lists the predefined alphabets.
+	Alphabets = []Alphabet{English, French, German, Spanish}
+)
+
+// AlphabetFor returns the predefined alphabet of the language identified by name,
+// e.g. "fr", and whether it exists.
+func AlphabetFor(name string) (Alphabet, bool) {
+	for _, a := range Alphabets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+
+	return Alphabet{}, false
+}
+
+// Valid returns true if every character of word is either a letter of the alphabet or
+// a separator shown from the start of the game.
+func (a Alphabet) Valid(word string) bool {
+	for _, r := range word {
+		if !isSeparator(r) && !a.Contains(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Contains returns true if r, or its lower case, is a letter of the alphabet.
+func (a Alphabet) Contains(r rune) bool {
+	if isSeparator(r) {
+		return false
+	}
+
+	if a.Letters == "" {
+		return true
+	}
+
+	return strings.ContainsRune(a.Letters, unicode.ToLower(r))
+}
+
+// Keys returns the distinct letters a player has to guess to find word, in order of
+// first appearance and as guesses are recorded: in lower case and folded to their
+// base letter if the alphabet folds accents.
+func (a Alphabet) Keys(word string) []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, r := range word {
+		if isSeparator(r) || seen[a.key(r)] {
+			continue
+		}
+
+		seen[a.key(r)] = true
+		keys = append(keys, a.key(r))
+	}
+
+	return keys
+}
+
+// key returns the letter a guess of r is recorded as: r in lower case, folded to its
+// base letter if the alphabet folds accents.
+func (a Alphabet) key(r rune) string {
+	r = unicode.ToLower(r)
+
+	if a.FoldAccents {
+		if base, ok := folds[r]; ok {
+			r = base
+		}
+	}
+
+	return string(r)
+}
+
+// normalize returns the keys of the letters of phrase, without separators, so that
+// phrases can be compared regardless of case, accents and punctuation.
+func (a Alphabet) normalize(phrase string) string {
+	var b strings.Builder
+	for _, r := range phrase {
+		if !isSeparator(r) {
+			b.WriteString(a.key(r))
+		}
+	}
+
+	return b.String()
+}
+
+// isSeparator returns true if r is shown from the start of a game rather than guessed.
+func isSeparator(r rune) bool {
+	return strings.ContainsRune(separators, r)
+}
@@ -0,0 +1,36 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskedWord(t *testing.T) {
+	state := New("spider-man's web", Normal, English)
+	assert.Equal(t, "_ _ _ _ _ _ - _ _ _ ' _   _ _ _ ", state.MaskedWord())
+
+	state = New("crème brûlée", Normal, French)
+	state.Guess("e")
+	state.Guess("u")
+	assert.Equal(t, "_ _ è _ e   _ _ û _ é e ", state.MaskedWord())
+}
+
+func TestAlphabetContains(t *testing.T) {
+	assert.True(t, English.Contains('a'))
+	assert.True(t, English.Contains('A'))
+	assert.False(t, English.Contains('é'))
+	assert.False(t, English.Contains(' '))
+	assert.True(t, German.Contains('ß'))
+	assert.True(t, Spanish.Contains('Ñ'))
+	assert.False(t, Spanish.Contains('ß'))
+	assert.True(t, Alphabet{}.Contains('ж'))
+	assert.False(t, Alphabet{}.Contains('-'))
+}
+
+func TestAlphabetKeys(t *testing.T) {
+	assert.Equal(t, []string{"b", "a", "t", "m", "n"}, English.Keys("Batman"))
+	assert.Equal(t, []string{"c", "r", "e", "m", "f", "a", "i", "h"}, French.Keys("crème fraîche"))
+	assert.Equal(t, []string{"a", "ñ", "o"}, Spanish.Keys("año"))
+	assert.Empty(t, English.Keys("- '"))
+}
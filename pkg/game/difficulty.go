@@ -0,0 +1,131 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Difficulty levels a player can choose from when starting a new game.
+const (
+	LevelEasy   = "easy"
+	LevelNormal = "normal"
+	LevelHard   = "hard"
+	LevelCustom = "custom"
+)
+
+// vowels are revealed from the start of the games whose difficulty asks for it.
+const vowels = "aeiou"
+
+// Difficulty holds the settings of a game. MaxMisses is the number of lives lost
+// that ends the game, SolveCost the number of lives a wrong solve costs, MinLength
+// and MaxLength bound the length of the word to guess (MaxLength is unbounded if 0),
+// RevealVowels reveals the vowels of the word from the start and Hints is the number
+// of hints the player can ask for.
+type Difficulty struct {
+	Level        string `json:"level"`
+	MaxMisses    int    `json:"max_misses"`
+	SolveCost    int    `json:"solve_cost"`
+	MinLength    int    `json:"min_length,omitempty"`
+	MaxLength    int    `json:"max_length,omitempty"`
+	RevealVowels bool   `json:"reveal_vowels,omitempty"`
+	Hints        int    `json:"hints"`
+}
+
+var (
+	// Easy games allow more mistakes on shorter words whose vowels are revealed.
+	Easy = Difficulty{Level: LevelEasy, MaxMisses: 10, SolveCost: 1, MaxLength: 8, RevealVowels: true, Hints: 3}
+
+	// Normal games follow the classic rules.
+	Normal = Difficulty{Level: LevelNormal, MaxMisses: MaxWrongChars, SolveCost: WrongSolveCost, Hints: 1}
+
+	// Hard games allow fewer mistakes on longer words and no hints.
+	Hard = Difficulty{Level: LevelHard, MaxMisses: 5, SolveCost: 3, MinLength: 10}
+
+	// Levels lists the predefined difficulty levels.
+	Levels = []Difficulty{Easy, Normal, Hard}
+)
+
+// ParseDifficulty returns the difficulty described by s: either the name of a
+// predefined level or "custom", optionally followed by a colon and a comma separated
+// list of settings overriding the normal ones, e.g.
+//
+//	custom:misses=5,solve=1,min=6,max=10,vowels=true,hints=2
+//
+// The normal difficulty is returned if s is empty.
+func ParseDifficulty(s string) (Difficulty, error) {
+	if s == "" {
+		return Normal, nil
+	}
+
+	for _, d := range Levels {
+		if s == d.Level {
+			return d, nil
+		}
+	}
+
+	settings := strings.TrimPrefix(s, LevelCustom)
+	if len(settings) == len(s) || settings != "" && settings[0] != ':' {
+		return Difficulty{}, fmt.Errorf("unknown difficulty %q. Valid difficulties are easy, normal, hard and custom", s)
+	}
+
+	d := Normal
+	d.Level = LevelCustom
+
+	for _, setting := range strings.Split(strings.TrimPrefix(settings, ":"), ",") {
+		if setting == "" {
+			continue
+		}
+
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 {
+			return Difficulty{}, fmt.Errorf("invalid difficulty setting %q, settings must be written as name=value", setting)
+		}
+
+		var err error
+		switch parts[0] {
+		case "misses":
+			d.MaxMisses, err = strconv.Atoi(parts[1])
+		case "solve":
+			d.SolveCost, err = strconv.Atoi(parts[1])
+		case "min":
+			d.MinLength, err = strconv.Atoi(parts[1])
+		case "max":
+			d.MaxLength, err = strconv.Atoi(parts[1])
+		case "vowels":
+			d.RevealVowels, err = strconv.ParseBool(parts[1])
+		case "hints":
+			d.Hints, err = strconv.Atoi(parts[1])
+		default:
+			return Difficulty{}, fmt.Errorf("unknown difficulty setting %q. Valid settings are misses, solve, min, max, vowels and hints", parts[0])
+		}
+		if err != nil {
+			return Difficulty{}, fmt.Errorf("invalid value for difficulty setting %q: %s", parts[0], parts[1])
+		}
+	}
+
+	return d, d.validate()
+}
+
+// Fits returns true if word can be used as the word to guess of a game of
+// difficulty d.
+func (d Difficulty) Fits(word string) bool {
+	n := utf8.RuneCountInString(word)
+
+	return n >= d.MinLength && (d.MaxLength == 0 || n <= d.MaxLength)
+}
+
+// validate ensures the settings of a custom difficulty make sense.
+func (d Difficulty) validate() error {
+	switch {
+	case d.MaxMisses < 1 || d.SolveCost < 1:
+		return fmt.Errorf("misses and solve must be at least 1")
+	case d.MinLength < 0 || d.MaxLength < 0 || d.Hints < 0:
+		return fmt.Errorf("min, max and hints cannot be negative")
+	case d.MaxLength != 0 && d.MaxLength < d.MinLength:
+		return fmt.Errorf("max cannot be lower than min")
+	}
+
+	return nil
+}
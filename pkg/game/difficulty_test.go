@@ -0,0 +1,66 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDifficulty(t *testing.T) {
+	testcases := []struct {
+		name     string
+		value    string
+		expected Difficulty
+		err      bool
+	}{
+		{name: "default", value: "", expected: Normal},
+		{name: "easy", value: "easy", expected: Easy},
+		{name: "hard", value: "hard", expected: Hard},
+		{name: "custom", value: "custom", expected: Difficulty{Level: LevelCustom, MaxMisses: MaxWrongChars, SolveCost: WrongSolveCost, Hints: 1}},
+		{
+			name:     "custom settings",
+			value:    "custom:misses=3,solve=1,min=4,max=6,vowels=true,hints=0",
+			expected: Difficulty{Level: LevelCustom, MaxMisses: 3, SolveCost: 1, MinLength: 4, MaxLength: 6, RevealVowels: true},
+		},
+		{name: "unknown level", value: "extreme", err: true},
+		{name: "custom prefix", value: "customized", err: true},
+		{name: "unknown setting", value: "custom:lives=3", err: true},
+		{name: "invalid value", value: "custom:misses=many", err: true},
+		{name: "no misses", value: "custom:misses=0", err: true},
+		{name: "free solves", value: "custom:solve=0", err: true},
+		{name: "inverted bounds", value: "custom:min=8,max=4", err: true},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			d, err := ParseDifficulty(testcase.value)
+			if testcase.err {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, testcase.expected, d)
+		})
+	}
+}
+
+func TestDifficultyFits(t *testing.T) {
+	assert.True(t, Easy.Fits("batman"))
+	assert.False(t, Easy.Fits("wonderwoman"))
+	assert.True(t, Hard.Fits("wonderwoman"))
+	assert.False(t, Hard.Fits("batman"))
+	assert.True(t, Normal.Fits("batman"))
+}
+
+func TestNewRevealVowels(t *testing.T) {
+	state := New("batman", Easy, English)
+
+	assert.Equal(t, []string{"a"}, state.CharsGuessed)
+	assert.Equal(t, "_ a _ _ a _ ", state.MaskedWord())
+	assert.Equal(t, 10, state.MaxMisses())
+
+	state = New("batman", Normal, English)
+	assert.Empty(t, state.CharsGuessed)
+	assert.Equal(t, MaxWrongChars, State{}.MaxMisses())
+}
@@ -0,0 +1,251 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Popcore/hangmango/pkg/utils"
+)
+
+// Outcome describes the effect of a guess on a game.
+type Outcome string
+
+const (
+	OutcomeHit     Outcome = "hit"
+	OutcomeMiss    Outcome = "miss"
+	OutcomeRepeat  Outcome = "repeat"
+	OutcomeInvalid Outcome = "invalid"
+	OutcomeWon     Outcome = "won"
+	OutcomeLost    Outcome = "lost"
+	OutcomeHint    Outcome = "hint"
+	OutcomeClue    Outcome = "clue"
+)
+
+var (
+	ErrorNotInProgress = errors.New("you must start a new game or resume a paused game before guessing the hero")
+	ErrorNoHintsLeft   = errors.New("you have no hints left in this game")
+	ErrorLastLife      = errors.New("a hint costs a life and you only have one left")
+)
+
+// New returns a game in progress of the given difficulty whose word to guess is word,
+// a single word or a phrase, written in alphabet. If the difficulty asks for it the
+// vowels of the word are revealed.
+func New(word string, difficulty Difficulty, alphabet Alphabet) State {
+	state := State{
+		WordToGuess: word,
+		CharsTried:  []string{},
+		Status:      InProgress,
+		Difficulty:  difficulty,
+		Alphabet:    alphabet,
+	}
+
+	if difficulty.RevealVowels {
+		for _, c := range word {
+			key := alphabet.key(c)
+			if strings.Contains(vowels, key) && !utils.Contains(state.CharsGuessed, key) {
+				state.CharsGuessed = append(state.CharsGuessed, key)
+			}
+		}
+	}
+
+	return state
+}
+
+// ValidGuess returns true if letter is a single letter of the alphabet of the game.
+func (g State) ValidGuess(letter string) bool {
+	r, _ := utf8.DecodeRuneInString(letter)
+
+	return utf8.RuneCountInString(letter) == 1 && g.Alphabet.Contains(r)
+}
+
+// Guess tries letter against the word to guess and updates the game accordingly.
+// A correct letter is added to the characters guessed and reveals all its
+// occurrences, while a wrong letter is added to the characters tried and costs a
+// life. Trying a letter again, right or wrong, leaves the game untouched and is
+// reported as a repeat. Guesses that are not a single letter of the game alphabet are
+// invalid. If the alphabet folds accents letters are recorded by their base letter.
+// A guess that ends the game is reported as won or lost. An error is returned if the
+// game is not in progress.
+func (g *State) Guess(letter string) (Outcome, error) {
+	if g.Status != InProgress {
+		return "", ErrorNotInProgress
+	}
+
+	if !g.ValidGuess(letter) {
+		return OutcomeInvalid, nil
+	}
+
+	r, _ := utf8.DecodeRuneInString(letter)
+	key := g.Alphabet.key(r)
+
+	var outcome Outcome
+
+	switch {
+	case utils.Contains(g.CharsGuessed, key) || utils.Contains(g.CharsTried, key):
+		return OutcomeRepeat, nil
+
+	case g.contains(key):
+		g.CharsGuessed = append(g.CharsGuessed, key)
+		outcome = OutcomeHit
+
+	default:
+		g.CharsTried = append(g.CharsTried, key)
+		outcome = OutcomeMiss
+	}
+
+	g.Status = g.status()
+
+	switch g.Status {
+	case Won:
+		return OutcomeWon, nil
+
+	case GameOver:
+		return OutcomeLost, nil
+	}
+
+	return outcome, nil
+}
+
+// Solve tries word as the whole word to guess and updates the game accordingly.
+// The right word reveals all the letters and wins the game, while a wrong word is
+// added to the words tried and costs as many lives as the game difficulty sets.
+// Words are compared regardless of case, separators and, if the alphabet folds them,
+// accents. Trying a wrong word again leaves the game untouched and is reported as a
+// repeat. Empty words are invalid. An error is returned if the game is not in
+// progress.
+func (g *State) Solve(word string) (Outcome, error) {
+	if g.Status != InProgress {
+		return "", ErrorNotInProgress
+	}
+
+	normalized := g.Alphabet.normalize(word)
+
+	switch {
+	case normalized == "":
+		return OutcomeInvalid, nil
+
+	case g.triedWord(normalized):
+		return OutcomeRepeat, nil
+
+	case normalized == g.Alphabet.normalize(g.WordToGuess):
+		for _, c := range g.WordToGuess {
+			if !g.revealed(c) {
+				g.CharsGuessed = append(g.CharsGuessed, g.Alphabet.key(c))
+			}
+		}
+
+	default:
+		g.WordsTried = append(g.WordsTried, word)
+	}
+
+	g.Status = g.status()
+
+	switch g.Status {
+	case Won:
+		return OutcomeWon, nil
+
+	case GameOver:
+		return OutcomeLost, nil
+	}
+
+	return OutcomeMiss, nil
+}
+
+// Hint reveals a letter of the word to guess that has not been guessed yet and
+// returns it. If the word has a clue the first hint reveals the clue instead, and
+// an empty letter is returned. Each hint costs a life, and the number of hints a game
+// allows is set by its difficulty. A hint that reveals the last letter wins the game.
+// An error is returned if the game is not in progress, if no hints are left or if the
+// hint would cost the last life.
+func (g *State) Hint() (string, Outcome, error) {
+	if g.Status != InProgress {
+		return "", "", ErrorNotInProgress
+	}
+
+	if g.HintsLeft() <= 0 {
+		return "", "", ErrorNoHintsLeft
+	}
+
+	if g.Misses()+1 >= g.MaxMisses() {
+		return "", "", ErrorLastLife
+	}
+
+	if g.Clue != "" && !g.ClueShown {
+		g.ClueShown = true
+		g.HintsUsed++
+
+		return "", OutcomeClue, nil
+	}
+
+	var letter string
+	for _, c := range g.WordToGuess {
+		if !g.revealed(c) {
+			letter = g.Alphabet.key(c)
+			break
+		}
+	}
+
+	g.CharsGuessed = append(g.CharsGuessed, letter)
+	g.HintsUsed++
+	g.Status = g.status()
+
+	if g.Status == Won {
+		return letter, OutcomeWon, nil
+	}
+
+	return letter, OutcomeHint, nil
+}
+
+// status checks if the game status should be set to game over, won or in progress.
+func (g State) status() Status {
+	if g.Misses() >= g.MaxMisses() {
+		return GameOver
+	}
+
+	if g.solved() {
+		return Won
+	}
+
+	return InProgress
+}
+
+// solved returns true if every letter of the word to guess has been revealed.
+func (g State) solved() bool {
+	for _, c := range g.WordToGuess {
+		if !g.revealed(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// revealed returns true if c is shown to the player: either it is a separator or
+// it has been guessed.
+func (g State) revealed(c rune) bool {
+	return isSeparator(c) || utils.Contains(g.CharsGuessed, g.Alphabet.key(c))
+}
+
+// contains returns true if a letter of the word to guess is recorded as key.
+func (g State) contains(key string) bool {
+	for _, c := range g.WordToGuess {
+		if g.Alphabet.key(c) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// triedWord returns true if a wrong word normalized as normalized has already been
+// tried.
+func (g State) triedWord(normalized string) bool {
+	for _, w := range g.WordsTried {
+		if g.Alphabet.normalize(w) == normalized {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,284 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuess(t *testing.T) {
+	testcases := []struct {
+		name     string
+		state    State
+		letter   string
+		outcome  Outcome
+		err      error
+		expected State
+	}{
+		{
+			name:     "hit",
+			state:    New("batman", Normal, English),
+			letter:   "a",
+			outcome:  OutcomeHit,
+			expected: State{WordToGuess: "batman", CharsGuessed: []string{"a"}, CharsTried: []string{}, Status: InProgress, Difficulty: Normal, Alphabet: English},
+		},
+		{
+			name:     "repeated hit",
+			state:    State{WordToGuess: "batman", CharsGuessed: []string{"a"}, CharsTried: []string{}, Status: InProgress},
+			letter:   "a",
+			outcome:  OutcomeRepeat,
+			expected: State{WordToGuess: "batman", CharsGuessed: []string{"a"}, CharsTried: []string{}, Status: InProgress},
+		},
+		{
+			name:     "miss",
+			state:    New("batman", Normal, English),
+			letter:   "z",
+			outcome:  OutcomeMiss,
+			expected: State{WordToGuess: "batman", CharsTried: []string{"z"}, Status: InProgress, Difficulty: Normal, Alphabet: English},
+		},
+		{
+			name:     "repeated miss",
+			state:    State{WordToGuess: "batman", CharsTried: []string{"z"}, Status: InProgress},
+			letter:   "z",
+			outcome:  OutcomeRepeat,
+			expected: State{WordToGuess: "batman", CharsTried: []string{"z"}, Status: InProgress},
+		},
+		{
+			name:     "empty guess",
+			state:    New("batman", Normal, English),
+			letter:   "",
+			outcome:  OutcomeInvalid,
+			expected: New("batman", Normal, English),
+		},
+		{
+			name:     "not in the alphabet",
+			state:    New("batman", Normal, English),
+			letter:   "1",
+			outcome:  OutcomeInvalid,
+			expected: New("batman", Normal, English),
+		},
+		{
+			name:     "separator",
+			state:    New("spider-man", Normal, Alphabet{}),
+			letter:   "-",
+			outcome:  OutcomeInvalid,
+			expected: New("spider-man", Normal, Alphabet{}),
+		},
+		{
+			name:     "folded accent",
+			state:    New("éclair", Normal, French),
+			letter:   "e",
+			outcome:  OutcomeHit,
+			expected: State{WordToGuess: "éclair", CharsGuessed: []string{"e"}, CharsTried: []string{}, Status: InProgress, Difficulty: Normal, Alphabet: French},
+		},
+		{
+			name:     "accented guess",
+			state:    New("eclair", Normal, French),
+			letter:   "É",
+			outcome:  OutcomeHit,
+			expected: State{WordToGuess: "eclair", CharsGuessed: []string{"e"}, CharsTried: []string{}, Status: InProgress, Difficulty: Normal, Alphabet: French},
+		},
+		{
+			name:     "distinct letter",
+			state:    New("niño", Normal, Spanish),
+			letter:   "n",
+			outcome:  OutcomeHit,
+			expected: State{WordToGuess: "niño", CharsGuessed: []string{"n"}, CharsTried: []string{}, Status: InProgress, Difficulty: Normal, Alphabet: Spanish},
+		},
+		{
+			name:     "phrase won",
+			state:    State{WordToGuess: "spider-man's", CharsGuessed: []string{"s", "p", "i", "d", "e", "r", "m", "n"}, Status: InProgress},
+			letter:   "a",
+			outcome:  OutcomeWon,
+			expected: State{WordToGuess: "spider-man's", CharsGuessed: []string{"s", "p", "i", "d", "e", "r", "m", "n", "a"}, Status: Won},
+		},
+		{
+			name:     "several characters",
+			state:    New("batman", Normal, English),
+			letter:   "ab",
+			outcome:  OutcomeInvalid,
+			expected: New("batman", Normal, English),
+		},
+		{
+			name:     "won",
+			state:    State{WordToGuess: "bob", CharsGuessed: []string{"b"}, CharsTried: []string{}, Status: InProgress},
+			letter:   "o",
+			outcome:  OutcomeWon,
+			expected: State{WordToGuess: "bob", CharsGuessed: []string{"b", "o"}, CharsTried: []string{}, Status: Won},
+		},
+		{
+			name:     "lost",
+			state:    State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3", "4", "5", "6"}, Status: InProgress},
+			letter:   "7",
+			outcome:  OutcomeLost,
+			expected: State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3", "4", "5", "6", "7"}, Status: GameOver},
+		},
+		{
+			name:     "lost on hard",
+			state:    State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3", "4"}, Status: InProgress, Difficulty: Hard},
+			letter:   "5",
+			outcome:  OutcomeLost,
+			expected: State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3", "4", "5"}, Status: GameOver, Difficulty: Hard},
+		},
+		{
+			name:     "miss on easy",
+			state:    State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3", "4", "5", "6"}, Status: InProgress, Difficulty: Easy},
+			letter:   "7",
+			outcome:  OutcomeMiss,
+			expected: State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3", "4", "5", "6", "7"}, Status: InProgress, Difficulty: Easy},
+		},
+		{
+			name:     "paused game",
+			state:    State{WordToGuess: "bob", Status: Paused},
+			letter:   "b",
+			err:      ErrorNotInProgress,
+			expected: State{WordToGuess: "bob", Status: Paused},
+		},
+		{
+			name:     "finished game",
+			state:    State{WordToGuess: "bob", Status: Won},
+			letter:   "b",
+			err:      ErrorNotInProgress,
+			expected: State{WordToGuess: "bob", Status: Won},
+		},
+	}
+
+	for _, testcase := range testcases {
+		state := testcase.state
+
+		outcome, err := state.Guess(testcase.letter)
+		assert.Equal(t, testcase.err, err, testcase.name)
+		assert.Equal(t, testcase.outcome, outcome, testcase.name)
+		assert.Equal(t, testcase.expected, state, testcase.name)
+	}
+}
+
+func TestSolve(t *testing.T) {
+	testcases := []struct {
+		name     string
+		state    State
+		word     string
+		outcome  Outcome
+		err      error
+		expected State
+	}{
+		{
+			name:     "right word",
+			state:    State{WordToGuess: "bob", CharsGuessed: []string{"o"}, Status: InProgress},
+			word:     "bob",
+			outcome:  OutcomeWon,
+			expected: State{WordToGuess: "bob", CharsGuessed: []string{"o", "b"}, Status: Won},
+		},
+		{
+			name:     "wrong word",
+			state:    State{WordToGuess: "bob", Status: InProgress, Difficulty: Normal},
+			word:     "bib",
+			outcome:  OutcomeMiss,
+			expected: State{WordToGuess: "bob", WordsTried: []string{"bib"}, Status: InProgress, Difficulty: Normal},
+		},
+		{
+			name:     "phrase regardless of case, accents and separators",
+			state:    State{WordToGuess: "la belle-époque", Status: InProgress, Alphabet: French},
+			word:     "La Belle Epoque",
+			outcome:  OutcomeWon,
+			expected: State{WordToGuess: "la belle-époque", CharsGuessed: []string{"l", "a", "b", "e", "p", "o", "q", "u"}, Status: Won, Alphabet: French},
+		},
+		{
+			name:     "repeated wrong word",
+			state:    State{WordToGuess: "bob", WordsTried: []string{"bib"}, Status: InProgress},
+			word:     "bib",
+			outcome:  OutcomeRepeat,
+			expected: State{WordToGuess: "bob", WordsTried: []string{"bib"}, Status: InProgress},
+		},
+		{
+			name:     "wrong word loses",
+			state:    State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3"}, Status: InProgress, Difficulty: Hard},
+			word:     "bib",
+			outcome:  OutcomeLost,
+			expected: State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3"}, WordsTried: []string{"bib"}, Status: GameOver, Difficulty: Hard},
+		},
+		{
+			name:     "empty word",
+			state:    State{WordToGuess: "bob", Status: InProgress},
+			word:     "",
+			outcome:  OutcomeInvalid,
+			expected: State{WordToGuess: "bob", Status: InProgress},
+		},
+		{
+			name:     "finished game",
+			state:    State{WordToGuess: "bob", Status: GameOver},
+			word:     "bob",
+			err:      ErrorNotInProgress,
+			expected: State{WordToGuess: "bob", Status: GameOver},
+		},
+	}
+
+	for _, testcase := range testcases {
+		state := testcase.state
+
+		outcome, err := state.Solve(testcase.word)
+		assert.Equal(t, testcase.err, err, testcase.name)
+		assert.Equal(t, testcase.outcome, outcome, testcase.name)
+		assert.Equal(t, testcase.expected, state, testcase.name)
+	}
+}
+
+func TestHint(t *testing.T) {
+	testcases := []struct {
+		name     string
+		state    State
+		letter   string
+		outcome  Outcome
+		err      error
+		expected State
+	}{
+		{
+			name:     "hint",
+			state:    State{WordToGuess: "bob", CharsGuessed: []string{"b"}, Status: InProgress, Difficulty: Normal},
+			letter:   "o",
+			outcome:  OutcomeWon,
+			expected: State{WordToGuess: "bob", CharsGuessed: []string{"b", "o"}, HintsUsed: 1, Status: Won, Difficulty: Normal},
+		},
+		{
+			name:     "hint in progress",
+			state:    State{WordToGuess: "bob", Status: InProgress, Difficulty: Easy},
+			letter:   "b",
+			outcome:  OutcomeHint,
+			expected: State{WordToGuess: "bob", CharsGuessed: []string{"b"}, HintsUsed: 1, Status: InProgress, Difficulty: Easy},
+		},
+		{
+			name:     "no hints left",
+			state:    State{WordToGuess: "bob", HintsUsed: 1, Status: InProgress, Difficulty: Normal},
+			err:      ErrorNoHintsLeft,
+			expected: State{WordToGuess: "bob", HintsUsed: 1, Status: InProgress, Difficulty: Normal},
+		},
+		{
+			name:     "no hints on hard",
+			state:    State{WordToGuess: "bob", Status: InProgress, Difficulty: Hard},
+			err:      ErrorNoHintsLeft,
+			expected: State{WordToGuess: "bob", Status: InProgress, Difficulty: Hard},
+		},
+		{
+			name:     "last life",
+			state:    State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3", "4", "5", "6"}, Status: InProgress, Difficulty: Normal},
+			err:      ErrorLastLife,
+			expected: State{WordToGuess: "bob", CharsTried: []string{"1", "2", "3", "4", "5", "6"}, Status: InProgress, Difficulty: Normal},
+		},
+		{
+			name:     "paused game",
+			state:    State{WordToGuess: "bob", Status: Paused, Difficulty: Normal},
+			err:      ErrorNotInProgress,
+			expected: State{WordToGuess: "bob", Status: Paused, Difficulty: Normal},
+		},
+	}
+
+	for _, testcase := range testcases {
+		state := testcase.state
+
+		letter, outcome, err := state.Hint()
+		assert.Equal(t, testcase.err, err, testcase.name)
+		assert.Equal(t, testcase.letter, letter, testcase.name)
+		assert.Equal(t, testcase.outcome, outcome, testcase.name)
+		assert.Equal(t, testcase.expected, state, testcase.name)
+	}
+}
@@ -3,33 +3,51 @@ package game
 import (
 	"encoding/json"
 	"fmt"
-
-	"github.com/Popcore/hangmango/pkg/utils"
 )
 
 const (
 	// MaxWrongChars is the number of failed attempts - or wrong characters -
-	// a player can make before the game is considered over.
+	// a player can make before a normal game is considered over.
 	MaxWrongChars = 7
+
+	// WrongSolveCost is the number of lives a wrong solve costs in a normal game.
+	WrongSolveCost = 2
 )
 
 var (
 	// Rules is a help message that descibes the rules of the game and the
 	// commands available to players.
 	Rules = fmt.Sprintf(`
-	GUESS THE CARTOON HERO!
+	GUESS THE WORD!
 
 	Rules:
-	Guessing the letters in order to discover who the hidden cartoon hero is.
-	If you make more that %d mistakes you loose.
+	Guessing the letters in order to discover the hidden word. Words are cartoon heroes unless you pick another category.
+	If you make as many mistakes as the difficulty of the game allows (%d in normal games) you loose.
 	************************************************************************************
 	Available commands:
-	help             => prints the help screen
-	new              => starts a new game
-	list             => shows the game history. Each game displays its id and status
-	try <character>  => checks if <character> is part of the word to guess
-	resume <game-id> => restarts an existing game if its staus is not 'won' or 'game over'
-`, MaxWrongChars)
+	help              => prints the help screen
+	new [category] [difficulty] [adaptive]
+	                  => starts a new game. Category defaults to heroes, difficulty is easy, normal
+	                     (default), hard or custom. Adaptive games pick harder words as you win
+	list              => shows the game history. Each game displays its id and status
+	try <character>   => checks if <character> is part of the word to guess
+	solve <word>      => guesses the whole word. A wrong word costs %d lives in normal games
+	hint              => reveals a letter, or the clue of the word, for a life. Easy games allow
+	                     3 hints, normal 1, hard none
+	resume <game-id>  => restarts an existing game if its staus is not 'won' or 'game over'
+	ping              => checks the connection to the server is alive
+	reconnect <token> => resumes the session identified by the token received on login
+	logout            => saves the current game and ends the session
+`, MaxWrongChars, WrongSolveCost)
+)
+
+const (
+	// ModeClassic games pick words at random.
+	ModeClassic = "classic"
+
+	// ModeAdaptive games pick words whose difficulty matches the recent win rate of
+	// the player.
+	ModeAdaptive = "adaptive"
 )
 
 // PlayerAction is a custom type that represents the commands a player can
@@ -43,16 +61,47 @@ const (
 	ListGames  PlayerAction = "list"
 	Help       PlayerAction = "help"
 	Login      PlayerAction = "login"
+	Handshake  PlayerAction = "hello"
+	Ping       PlayerAction = "ping"
+	Reconnect  PlayerAction = "reconnect"
+	Logout     PlayerAction = "logout"
+	Solve      PlayerAction = "solve"
+	Hint       PlayerAction = "hint"
+)
+
+var (
+	// Actions lists the player actions supported by the game.
+	Actions = []PlayerAction{Handshake, Ping, Login, Reconnect, Logout, Help, NewGame, ListGames, ResumeGame, Guess, Solve, Hint}
+
+	// Modes lists the game modes a player can choose from.
+	Modes = []string{ModeClassic, ModeAdaptive}
 )
 
 // State holds information about game status and can be updated according to the
-// player's input.
+// player's input. CharsGuessed and CharsTried are sets: they hold the distinct
+// letters tried so far that are, respectively, part of the word or not. WordsTried
+// holds the wrong words the player tried to solve the game with and HintsUsed the
+// number of hints the player asked for. Alphabet holds the letters that can be
+// guessed and Category the category of the word. Clue is the clue of the word, if
+// it has one, and ClueShown is set once a hint revealed it. Source and Seed record
+// how the word was picked, to help investigating a game; they are not sent to
+// players. Revision counts the saves of the game, see store.Storer.
 type State struct {
-	GameID       int      `json:"id"`
-	WordToGuess  string   `json:"word"`
-	CharsGuessed []string `json:"guessed"`
-	CharsTried   []string `json:"tried"`
-	Status       Status   `json:"status"`
+	GameID       int        `json:"id"`
+	WordToGuess  string     `json:"word"`
+	CharsGuessed []string   `json:"guessed"`
+	CharsTried   []string   `json:"tried"`
+	WordsTried   []string   `json:"words_tried,omitempty"`
+	HintsUsed    int        `json:"hints_used"`
+	Status       Status     `json:"status"`
+	Difficulty   Difficulty `json:"difficulty"`
+	Alphabet     Alphabet   `json:"alphabet"`
+	Category     string     `json:"category,omitempty"`
+	Clue         string     `json:"clue,omitempty"`
+	ClueShown    bool       `json:"clue_shown,omitempty"`
+	Source       string     `json:"source,omitempty"`
+	Seed         int64      `json:"seed"`
+	Revision     int        `json:"revision"`
 }
 
 // Status represents the current status of a game. Its value can be one of the
@@ -69,13 +118,44 @@ const (
 	Error      Status = "error"
 )
 
-// MarshalJSON is the game State implementation of the JSON Marshaler interface.
-// The internal logic formats the word to guess by displaying the characters
-// that were guessed and hiding the characther still to guess.
-func (g State) MarshalJSON() ([]byte, error) {
+// MaxMisses returns the number of wrong characters that ends the game. Games saved
+// before difficulties were introduced follow the normal rules.
+func (g State) MaxMisses() int {
+	if g.Difficulty.MaxMisses <= 0 {
+		return MaxWrongChars
+	}
+
+	return g.Difficulty.MaxMisses
+}
+
+// SolveCost returns the number of lives a wrong solve costs. Games saved before
+// difficulties were introduced follow the normal rules.
+func (g State) SolveCost() int {
+	if g.Difficulty.SolveCost <= 0 {
+		return WrongSolveCost
+	}
+
+	return g.Difficulty.SolveCost
+}
+
+// Misses returns the number of lives lost so far: one for each wrong character and
+// hint, and SolveCost for each wrong word.
+func (g State) Misses() int {
+	return len(g.CharsTried) + len(g.WordsTried)*g.SolveCost() + g.HintsUsed
+}
+
+// HintsLeft returns the number of hints the player can still ask for.
+func (g State) HintsLeft() int {
+	return g.Difficulty.Hints - g.HintsUsed
+}
+
+// MaskedWord formats the word to guess by displaying the characters that were
+// guessed, as well as the spaces, hyphens and apostrophes of phrases, and hiding the
+// characters still to guess.
+func (g State) MaskedWord() string {
 	var p string
 	for _, c := range g.WordToGuess {
-		if utils.Contains(g.CharsGuessed, string(c)) {
+		if g.revealed(c) {
 			p += fmt.Sprintf("%s ", string(c))
 			continue
 		}
@@ -83,15 +163,36 @@ func (g State) MarshalJSON() ([]byte, error) {
 		p += "_ "
 	}
 
+	return p
+}
+
+// MarshalJSON is the game State implementation of the JSON Marshaler interface.
+// The word to guess is sent masked, see MaskedWord, and its clue only once shown.
+func (g State) MarshalJSON() ([]byte, error) {
+	clue := g.Clue
+	if !g.ClueShown {
+		clue = ""
+	}
+
 	return json.Marshal(&struct {
-		GameID      int      `json:"id"`
-		WordToGuess string   `json:"word"`
-		CharsTried  []string `json:"tried"`
-		Status      Status   `json:"status"`
+		GameID      int        `json:"id"`
+		WordToGuess string     `json:"word"`
+		CharsTried  []string   `json:"tried"`
+		WordsTried  []string   `json:"words_tried,omitempty"`
+		HintsUsed   int        `json:"hints_used"`
+		Status      Status     `json:"status"`
+		Difficulty  Difficulty `json:"difficulty"`
+		Category    string     `json:"category,omitempty"`
+		Clue        string     `json:"clue,omitempty"`
 	}{
 		GameID:      g.GameID,
-		WordToGuess: p,
+		WordToGuess: g.MaskedWord(),
 		CharsTried:  g.CharsTried,
+		WordsTried:  g.WordsTried,
+		HintsUsed:   g.HintsUsed,
 		Status:      g.Status,
+		Difficulty:  g.Difficulty,
+		Category:    g.Category,
+		Clue:        clue,
 	})
 }
@@ -1,9 +1,70 @@
 package messages
 
 import (
+	"encoding/json"
+
 	"github.com/Popcore/hangmango/pkg/game"
 )
 
+const (
+	// ProtocolVersion is the version of the messaging protocol spoken by this package.
+	ProtocolVersion = 1
+
+	// MinProtocolVersion is the oldest protocol version a client can negotiate.
+	MinProtocolVersion = 1
+
+	// LegacyProtocolVersion is the version of the clients that never handshake. They
+	// are sent bare responses rather than envelopes.
+	LegacyProtocolVersion = 0
+)
+
+// Type is the discriminator carried by every envelope sent by the server. It
+// tells the receiver how the envelope payload should be decoded.
+type Type string
+
+const (
+	HelpType      Type = "help"
+	GameStateType Type = "game_state"
+	ListGamesType Type = "list_games"
+	HandshakeType Type = "handshake"
+	PongType      Type = "pong"
+	SessionType   Type = "session"
+	ErrorType     Type = "error"
+)
+
+// Response is implemented by every payload the server can send to its clients.
+type Response interface {
+	MessageType() Type
+}
+
+// Envelope wraps every message sent by the server. RequestID echoes the ID of the
+// PlayerReq that originated the response and is empty for unsolicited messages.
+type Envelope struct {
+	Type      Type            `json:"type"`
+	RequestID string          `json:"request_id,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// NewEnvelope wraps resp in an Envelope tagged with its message type and the
+// originating request ID.
+func NewEnvelope(requestID string, resp Response) (*Envelope, error) {
+	payload, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		Type:      resp.MessageType(),
+		RequestID: requestID,
+		Payload:   payload,
+	}, nil
+}
+
+// Decode unmarshals the envelope payload into resp.
+func (e Envelope) Decode(resp interface{}) error {
+	return json.Unmarshal(e.Payload, resp)
+}
+
 // ListGamesResp is the server response type used when a user requires
 // the list of games played.
 type ListGamesResp struct {
@@ -11,27 +72,177 @@ type ListGamesResp struct {
 	Error *Error       `json:"error,omitempty"`
 }
 
+// MessageType implements the Response interface.
+func (ListGamesResp) MessageType() Type { return ListGamesType }
+
 // GameStateResp is the server response used to desctibe the current game
-// state.
+// state. Outcome is only set in response to a guess and Hint, the letter revealed,
+// in response to a hint.
 type GameStateResp struct {
-	State game.State `json:"game"`
-	Error *Error     `json:"error,omitempty"`
+	State   game.State   `json:"game"`
+	Outcome game.Outcome `json:"outcome,omitempty"`
+	Hint    string       `json:"hint,omitempty"`
+	Error   *Error       `json:"error,omitempty"`
 }
 
+// MessageType implements the Response interface.
+func (GameStateResp) MessageType() Type { return GameStateType }
+
 // HelpResp is the server response to a help request. Used to tell the user
-// the game rules and the availbale commands.
+// the game rules and the availbale commands. It is also the response to login
+// requests, in which case Token holds the session token issued to the player.
+// Categories lists the categories of words a player can choose from.
 type HelpResp struct {
-	Info  string `json:"info"`
-	Error *Error `json:"error,omitempty"`
+	Info       string   `json:"info"`
+	Categories []string `json:"categories,omitempty"`
+	Token      string   `json:"token,omitempty"`
+	Error      *Error   `json:"error,omitempty"`
+}
+
+// MessageType implements the Response interface.
+func (HelpResp) MessageType() Type { return HelpType }
+
+// Limits describes the constraints enforced by the server. IdleTimeoutSeconds is
+// zero if sessions never time out, otherwise clients must send a request, e.g. a
+// ping, at least that often to keep their session open.
+type Limits struct {
+	MaxWrongChars      int `json:"max_wrong_chars"`
+	MaxRequestBytes    int `json:"max_request_bytes"`
+	IdleTimeoutSeconds int `json:"idle_timeout_seconds,omitempty"`
+}
+
+// HandshakeResp is the server response to a handshake request. It tells the client
+// which protocol version will be used for the session and what the server supports.
+type HandshakeResp struct {
+	Version      int                 `json:"version"`
+	Actions      []game.PlayerAction `json:"actions"`
+	Modes        []string            `json:"modes"`
+	Difficulties []game.Difficulty   `json:"difficulties,omitempty"`
+	Categories   []string            `json:"categories,omitempty"`
+	Limits       Limits              `json:"limits"`
+	Error        *Error              `json:"error,omitempty"`
 }
 
+// MessageType implements the Response interface.
+func (HandshakeResp) MessageType() Type { return HandshakeType }
+
+// Supports returns true if action is one of the actions advertised by the server.
+func (h HandshakeResp) Supports(action game.PlayerAction) bool {
+	for _, a := range h.Actions {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SessionResp is the server response to reconnect and logout requests. After a
+// reconnection it holds the user the session belongs to and the game that was active
+// when the connection was lost, if any. It is empty after a logout.
+type SessionResp struct {
+	UserID string      `json:"user_id,omitempty"`
+	Token  string      `json:"token,omitempty"`
+	Game   *game.State `json:"game,omitempty"`
+	Error  *Error      `json:"error,omitempty"`
+}
+
+// MessageType implements the Response interface.
+func (SessionResp) MessageType() Type { return SessionType }
+
+// PongResp is the server response to a ping request.
+type PongResp struct{}
+
+// MessageType implements the Response interface.
+func (PongResp) MessageType() Type { return PongType }
+
 // PlayerReq is the payload send by clients. It must contain the action the
-// user wants to perform an an option value.
+// user wants to perform an an option value. ID is optional and, when set, is
+// echoed back in the envelope of every response to the request. Version is only
+// used by handshake requests.
 type PlayerReq struct {
-	Action game.PlayerAction `json:"action"`
-	Value  string            `json:"value"`
+	ID      string            `json:"id,omitempty"`
+	Action  game.PlayerAction `json:"action"`
+	Value   string            `json:"value"`
+	Version int               `json:"version,omitempty"`
 }
 
+// ErrorCode is a stable, machine readable identifier of the failure described
+// by an Error.
+type ErrorCode string
+
+const (
+	UnknownAction      ErrorCode = "unknown_action"
+	MalformedRequest   ErrorCode = "malformed_request"
+	RequestTooLarge    ErrorCode = "request_too_large"
+	UnsupportedVersion ErrorCode = "unsupported_version"
+	UserNotFound       ErrorCode = "user_not_found"
+	GameNotFound       ErrorCode = "game_not_found"
+	InvalidGameID      ErrorCode = "invalid_game_id"
+	NoActiveGame       ErrorCode = "no_active_game"
+	GameFinished       ErrorCode = "game_finished"
+	StaleGame          ErrorCode = "stale_game"
+	NoHintsLeft        ErrorCode = "no_hints_left"
+	InvalidGuess       ErrorCode = "invalid_guess"
+	InvalidDifficulty  ErrorCode = "invalid_difficulty"
+	UnknownCategory    ErrorCode = "unknown_category"
+	NotFound           ErrorCode = "not_found"
+	InvalidToken       ErrorCode = "invalid_token"
+	Forbidden          ErrorCode = "forbidden"
+	InternalError      ErrorCode = "internal_error"
+)
+
+// Error is sent when a request cannot be processed. Code identifies the failure,
+// Message is a human readable description and Details optionally holds extra
+// information such as the offending value.
 type Error struct {
-	Message string
+	Code    ErrorCode         `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// NewError returns an Error identified by code.
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// ErrorOf returns the error carried by resp, or nil if resp describes a success.
+func ErrorOf(resp Response) *Error {
+	switch r := resp.(type) {
+	case *Error:
+		return r
+	case HelpResp:
+		return r.Error
+	case GameStateResp:
+		return r.Error
+	case ListGamesResp:
+		return r.Error
+	case HandshakeResp:
+		return r.Error
+	case SessionResp:
+		return r.Error
+	}
+
+	return nil
+}
+
+// WithDetail adds the key-value pair to the error details and returns the error.
+func (e *Error) WithDetail(key, value string) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]string)
+	}
+	e.Details[key] = value
+
+	return e
+}
+
+// MessageType implements the Response interface.
+func (Error) MessageType() Type { return ErrorType }
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
 }
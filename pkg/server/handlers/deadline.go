@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultHandshakeTimeout is how long TLS clients have to complete their handshake
+	// when the System does not set its own timeout.
+	DefaultHandshakeTimeout = 10 * time.Second
+)
+
+// deadlineConn sets a write deadline on the wrapped connection before every write,
+// so that a client that stops reading cannot block its session forever.
+type deadlineConn struct {
+	net.Conn
+	writeTimeout time.Duration
+}
+
+// newDeadlineConn returns conn unchanged if writeTimeout is not set.
+func newDeadlineConn(conn net.Conn, writeTimeout time.Duration) net.Conn {
+	if writeTimeout <= 0 {
+		return conn
+	}
+
+	return &deadlineConn{
+		Conn:         conn,
+		writeTimeout: writeTimeout,
+	}
+}
+
+// Write writes p to the connection, failing if it takes longer than the write timeout.
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	err := c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	if err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Write(p)
+}
+
+// awaitRequest gives the client IdleTimeout to send its next request. Reads from
+// clients that stay silent longer than that fail with a timeout error.
+func (c *controller) awaitRequest() error {
+	if c.System.IdleTimeout <= 0 {
+		return nil
+	}
+
+	return c.Conn.SetReadDeadline(time.Now().Add(c.System.IdleTimeout))
+}
+
+// isClosed returns true if err was caused by using a closed connection, e.g. after
+// another connection took the session over or when the client has gone away.
+func isClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe)
+}
+
+// IsTimeout returns true if err was caused by a session deadline expiring.
+func IsTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+
+	return ok && netErr.Timeout()
+}
@@ -1,121 +1,598 @@
 package handlers
 
 import (
+	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Popcore/hangmango/pkg/certs"
 	"github.com/Popcore/hangmango/pkg/game"
 	"github.com/Popcore/hangmango/pkg/messages"
 	"github.com/Popcore/hangmango/pkg/store"
-	"github.com/Popcore/hangmango/pkg/utils"
+	"github.com/Popcore/hangmango/pkg/words"
+)
+
+var (
+	// defaultWords is the library used if System.Words is not set.
+	defaultWords = words.NewLibrary()
+
+	// defaultSource is the word source used if System.Source is not set.
+	defaultSource = words.NewUniform()
 )
 
 // System holds services and configuration settings required by the game controller.
+// MaxRequestSize caps the size in bytes of a single client request, DefaultMaxRequestSize
+// is used if it is not set. IdleTimeout is how long a session waits for the next
+// request and WriteTimeout how long writing a single response can take before the
+// session is closed. Sessions never time out if they are not set. HandshakeTimeout is
+// how long TLS clients have to complete their handshake, DefaultHandshakeTimeout is
+// used if it is not set. Session tokens are only issued on login when Sessions is
+// set. Words holds the word packs players can choose from, only the default pack is
+// available if it is not set. Source picks the words to guess, uniformly at random if
+// it is not set. When NoRepeat is set players are not given a word they already
+// played until they played all the words of the pack, see words.Pack.Pick.
 type System struct {
-	Logger *log.Logger
-	Store  store.Storer
+	Logger           *log.Logger
+	Store            store.Storer
+	Sessions         *Sessions
+	MaxRequestSize   int
+	IdleTimeout      time.Duration
+	WriteTimeout     time.Duration
+	HandshakeTimeout time.Duration
+	Words            *words.Library
+	Source           words.WordSource
+	NoRepeat         bool
+}
+
+// maxRequestSize returns the maximum size in bytes of a single client request.
+func (s System) maxRequestSize() int {
+	if s.MaxRequestSize <= 0 {
+		return DefaultMaxRequestSize
+	}
+
+	return s.MaxRequestSize
+}
+
+// handshakeTimeout returns how long TLS clients have to complete their handshake.
+func (s System) handshakeTimeout() time.Duration {
+	if s.HandshakeTimeout <= 0 {
+		return DefaultHandshakeTimeout
+	}
+
+	return s.HandshakeTimeout
+}
+
+// words returns the library of the word packs players can choose from.
+func (s System) words() *words.Library {
+	if s.Words == nil {
+		return defaultWords
+	}
+
+	return s.Words
+}
+
+// source returns the source picking the words to guess.
+func (s System) source() words.WordSource {
+	if s.Source == nil {
+		return defaultSource
+	}
+
+	return s.Source
 }
 
 // controller holds all the required information in order to manage game sessions
-// for a connected user.
+// for a connected user. CertUserID is set when the client authenticated with a TLS
+// certificate, in which case it is used as the UserID on login. Token is the session
+// token held by the connection. Version is the protocol version negotiated by the
+// handshake, messages.LegacyProtocolVersion until then.
 type controller struct {
-	Conn      net.Conn
-	System    System
-	UserID    string
-	GameState *game.State
-	Encoder   *json.Encoder
+	Conn       net.Conn
+	System     System
+	UserID     string
+	CertUserID string
+	Token      string
+	GameState  *game.State
+	Encoder    *json.Encoder
+	Reader     *requestReader
+	RPC        *rpcSession
+	Text       *textSession
+	RequestID  string
+	Version    int
 }
 
 // NewSession returns a controller instance that cen be used to manage games.
-// It is the actual game entry point.
+// It is the actual game entry point. When the session ends, because the client
+// disconnected or timed out, the game in progress is paused and saved so that it
+// can be resumed later, and the session token is released so that the player can
+// reconnect to it.
 func NewSession(System System, conn net.Conn) error {
+	certUserID, err := peerIdentity(conn, System.handshakeTimeout())
+	if err != nil {
+		return err
+	}
+
+	conn = newDeadlineConn(conn, System.WriteTimeout)
+
 	h := &controller{
-		System:  System,
-		Conn:    conn,
-		Encoder: json.NewEncoder(conn),
+		CertUserID: certUserID,
+		System:     System,
+		Conn:       conn,
+		Encoder:    json.NewEncoder(conn),
+		Reader:     newRequestReader(conn, System.maxRequestSize()),
+		Version:    messages.LegacyProtocolVersion,
+	}
+
+	err = h.handleGameIO()
+	if IsTimeout(err) {
+		System.Logger.Printf("session of user %s timed out: %v", h.UserID, err)
 	}
 
-	return h.handleGameIO()
+	h.detach()
+
+	return err
+}
+
+// detach saves the game in progress and releases the session token, recording the
+// game as the one to resume when the player reconnects.
+func (c *controller) detach() {
+	var gameID int
+	if c.GameState != nil && c.GameState.Status == game.InProgress {
+		gameID = c.GameState.GameID
+	}
+
+	c.autosave()
+
+	if c.Token != "" {
+		c.System.Sessions.Release(c.Token, c.Conn, gameID)
+	}
+}
+
+// autosave pauses and saves the game in progress, if any, logging failures since
+// the session is ending and the player cannot be told.
+func (c *controller) autosave() {
+	err := c.pauseGame()
+	if err != nil {
+		c.System.Logger.Printf("error saving game %d of user %s: %v", c.GameState.GameID, c.UserID, err)
+	}
+}
+
+// pauseGame pauses and saves the game in progress before the player leaves it.
+// Finished games are left untouched since they were saved by their last move.
+func (c *controller) pauseGame() error {
+	if c.UserID == "" || c.GameState == nil {
+		return nil
+	}
+
+	err := c.reloadGame()
+	if err != nil {
+		return err
+	}
+
+	if c.GameState.Status != game.InProgress {
+		return nil
+	}
+
+	c.GameState.Status = game.Paused
+
+	err = c.saveGame()
+	if err != nil {
+		return err
+	}
+
+	c.System.Logger.Printf("game %d of user %s saved", c.GameState.GameID, c.UserID)
+
+	return nil
+}
+
+// reloadGame replaces the current game with its stored version, so that moves made
+// over HTTP while the session had the game open are not lost. Games that were never
+// saved are left as they are.
+func (c *controller) reloadGame() error {
+	if c.GameState == nil || c.GameState.GameID == 0 {
+		return nil
+	}
+
+	stored, err := c.System.Store.GetGameByID(c.UserID, c.GameState.GameID)
+	if err != nil {
+		return err
+	}
+
+	c.GameState = stored
+
+	return nil
+}
+
+// saveGame persists the current game, so that every change survives disconnections.
+// Saving fails with store.ErrorStaleGame if the game was changed since it was loaded.
+func (c *controller) saveGame() error {
+	saved, err := c.System.Store.SaveGame(c.UserID, *c.GameState)
+	if err != nil {
+		return err
+	}
+
+	c.GameState = saved
+
+	return nil
 }
 
 // handleGameIO glues together the input parsing, processing and response processes.
-func (c *controller) handleGameIO() (err error) {
+// Requests are read through the session reader so that messages pipelined by the
+// client are processed, and answered, in the order they were sent. Malformed requests
+// are answered with an error and the session carries on with the next request.
+// The first message selects the protocol of the session: plain text if it does not
+// start with a JSON value, JSON-RPC 2.0 if it is a JSON-RPC request or batch, plain
+// PlayerReq messages otherwise.
+func (c *controller) handleGameIO() error {
+	first := true
+
+	err := c.awaitRequest()
+	if err != nil {
+		return err
+	}
+
+	b, err := c.Reader.peek()
+	if err != nil {
+		if err == io.EOF {
+			c.System.Logger.Printf("user %s disconnected", c.UserID)
+		}
+
+		return err
+	}
+
+	if isText(b) {
+		c.System.Logger.Println("client selected the plain text protocol")
+		c.Text = &textSession{Writer: c.Conn}
+
+		return c.handleTextIO()
+	}
 
 	for {
-		cmd, err := parseUserInput(c.Conn)
+		err := c.awaitRequest()
+		if err != nil {
+			return err
+		}
+
+		raw, err := c.Reader.next()
 		if err != nil {
-			if err == io.EOF {
+			if err == io.EOF || err == io.ErrUnexpectedEOF || isClosed(err) {
 				c.System.Logger.Printf("user %s disconnected", c.UserID)
-				break
+				return io.EOF
+			}
+
+			if err != errNotAnObject && err != errRequestTooLarge {
+				return err
+			}
+
+			c.System.Logger.Printf("malformed request from user %s: %v", c.UserID, err)
+
+			err = c.requestError(requestError(err, c.System.maxRequestSize()))
+			if err != nil {
+				return err
 			}
 
-			c.System.Logger.Println(err)
-			c.Encoder.Encode(err)
+			continue
+		}
+
+		if first && isRPC(raw) {
+			c.System.Logger.Println("client selected the JSON-RPC 2.0 protocol")
+			c.RPC = &rpcSession{Encoder: c.Encoder}
 		}
+		first = false
 
-		err = c.handlePlayerAction(*cmd)
+		if c.RPC != nil {
+			err = c.RPC.handle(c, raw)
+			if err != nil {
+				if isClosed(err) {
+					c.System.Logger.Printf("user %s disconnected", c.UserID)
+					return io.EOF
+				}
+
+				return err
+			}
+
+			continue
+		}
+
+		cmd, err := parseUserInput(raw)
 		if err != nil {
-			if err == io.EOF {
+			c.System.Logger.Printf("malformed request from user %s: %v", c.UserID, err)
+
+			err = c.requestError(requestError(err, c.System.maxRequestSize()))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err = c.dispatch(*cmd)
+		if err != nil {
+			if err == io.EOF || isClosed(err) {
 				c.System.Logger.Printf("user %s disconnected", c.UserID)
-				break
+				return io.EOF
 			}
-			c.System.Logger.Println(err)
-			c.Encoder.Encode(err)
+
+			return err
 		}
 	}
+}
 
-	return err
+// dispatch calls the handler of the player action recovering from any panic raised
+// while processing the request, so that a faulty request cannot take the session down.
+func (c *controller) dispatch(input messages.PlayerReq) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.System.Logger.Printf("panic handling action %q of user %s: %v\n%s", input.Action, c.UserID, r, debug.Stack())
+
+			err = c.respond(messages.NewError(messages.InternalError, "the request could not be processed"))
+		}
+	}()
+
+	return c.handlePlayerAction(input)
+}
+
+// requestError answers a request that could not be read or decoded. Since the
+// request ID is unknown the error is not correlated to any request.
+func (c *controller) requestError(respErr *messages.Error) error {
+	if c.RPC != nil {
+		return c.RPC.parseError(respErr)
+	}
+
+	c.RequestID = ""
+
+	return c.respond(respErr)
+}
+
+// respond wraps resp in an envelope stamped with the ID of the request being
+// processed and sends it to the player. JSON-RPC sessions receive a JSON-RPC
+// response and plain text sessions a human readable one instead. Sessions that did
+// not negotiate a protocol version with a handshake receive resp bare, as legacy
+// clients expect.
+func (c *controller) respond(resp messages.Response) error {
+	if c.RPC != nil {
+		return c.RPC.respond(c.RequestID, resp)
+	}
+
+	if c.Text != nil {
+		return c.Text.respond(resp)
+	}
+
+	if c.Version == messages.LegacyProtocolVersion {
+		return c.Encoder.Encode(resp)
+	}
+
+	envelope, err := messages.NewEnvelope(c.RequestID, resp)
+	if err != nil {
+		return err
+	}
+
+	return c.Encoder.Encode(envelope)
+}
+
+// handshakeHandler negotiates the protocol version used for the rest of the session
+// and advertises the actions, game modes and limits supported by the server. Clients
+// older than the minimum supported protocol version receive an error, enveloped since
+// they asked for the envelope protocol, and the session version does not change.
+func (c *controller) handshakeHandler(version int) error {
+	c.System.Logger.Printf("client requested protocol version %d", version)
+
+	if version < messages.MinProtocolVersion {
+		negotiated := c.Version
+		c.Version = messages.ProtocolVersion
+		defer func() { c.Version = negotiated }()
+
+		return c.respond(messages.HandshakeResp{
+			Version: messages.ProtocolVersion,
+			Error: messages.NewError(
+				messages.UnsupportedVersion,
+				fmt.Sprintf("protocol version %d is not supported. Minimum version is %d", version, messages.MinProtocolVersion),
+			).WithDetail("version", strconv.Itoa(version)),
+		})
+	}
+
+	c.Version = version
+	if c.Version > messages.ProtocolVersion {
+		c.Version = messages.ProtocolVersion
+	}
+
+	return c.respond(messages.HandshakeResp{
+		Version:      c.Version,
+		Actions:      game.Actions,
+		Modes:        game.Modes,
+		Difficulties: game.Levels,
+		Categories:   c.System.words().Categories(),
+		Limits: messages.Limits{
+			MaxWrongChars:      game.MaxWrongChars,
+			MaxRequestBytes:    c.System.maxRequestSize(),
+			IdleTimeoutSeconds: int(c.System.IdleTimeout / time.Second),
+		},
+	})
+}
+
+// pingHandler answers the keepalive messages sent by clients. Any request resets
+// the idle timeout, ping allows clients to do so without side effects.
+func (c *controller) pingHandler() error {
+	return c.respond(messages.PongResp{})
 }
 
 // loginHandler sets the controller UserID using the name received from
 // the user.
 func (c *controller) loginHandler(userName string) error {
+	if c.CertUserID != "" && c.CertUserID != userName {
+		c.System.Logger.Printf("user %s authenticated by certificate as %s", userName, c.CertUserID)
+	}
+	if c.CertUserID != "" {
+		userName = c.CertUserID
+	}
+
 	c.System.Logger.Printf("user authenticated: %s", userName)
 
 	err := c.System.Store.SaveNewUser(userName)
 	if err != nil {
-		return c.Encoder.Encode(messages.HelpResp{
-			Error: &messages.Error{Message: err.Error()},
+		return c.respond(messages.HelpResp{
+			Error: toError(err),
 		})
 	}
 
+	if c.UserID != "" {
+		// switching users leaves the game of the previous one as logging out does,
+		// and issueToken revokes its token
+		c.autosave()
+		c.GameState = nil
+	}
+
 	c.UserID = userName
 
-	return c.Encoder.Encode(messages.HelpResp{Info: game.Rules})
+	token, err := c.issueToken()
+	if err != nil {
+		return c.respond(messages.HelpResp{
+			Error: toError(err),
+		})
+	}
+
+	return c.respond(messages.HelpResp{
+		Info:       game.Rules,
+		Categories: c.System.words().Categories(),
+		Token:      token,
+	})
 }
 
-// newGameHandler returns a new game as saves the previous game if is not nil.
-func (c *controller) newGameHandler() error {
-	c.System.Logger.Printf("%s is starting a new game", c.UserID)
+// issueToken issues a new session token for the current user, replacing the token
+// previously held by the connection. No token is issued if Sessions is not set.
+func (c *controller) issueToken() (string, error) {
+	if c.System.Sessions == nil {
+		return "", nil
+	}
 
-	if c.GameState != nil {
-		c.GameState.Status = game.Paused
+	if c.Token != "" {
+		c.System.Sessions.Revoke(c.Token)
+		c.Token = ""
+	}
 
-		_, err := c.System.Store.SaveGame(c.UserID, *c.GameState)
+	token, err := c.System.Sessions.Issue(c.UserID, c.Conn)
+	if err != nil {
+		return "", err
+	}
+
+	c.Token = token
+
+	return token, nil
+}
+
+// reconnectHandler resumes the session identified by token, issued on login to a
+// connection that has since been lost. The session takes over the user and the game
+// that was active when the connection was lost.
+func (c *controller) reconnectHandler(token string) error {
+	c.System.Logger.Printf("client is reconnecting to a previous session")
+
+	if c.System.Sessions == nil {
+		return c.respond(messages.SessionResp{
+			Error: messages.NewError(messages.InvalidToken, "session tokens are not enabled"),
+		})
+	}
+
+	userID, gameID, err := c.System.Sessions.Claim(token, c.Conn)
+	if err != nil {
+		return c.respond(messages.SessionResp{
+			Error: messages.NewError(messages.InvalidToken, err.Error()),
+		})
+	}
+
+	if c.CertUserID != "" && c.CertUserID != userID {
+		c.System.Sessions.Release(token, c.Conn, gameID)
+
+		return c.respond(messages.SessionResp{
+			Error: messages.NewError(messages.InvalidToken, "session token was issued to another user"),
+		})
+	}
+
+	if c.Token != token {
+		c.detach()
+	}
+
+	c.UserID = userID
+	c.Token = token
+	c.GameState = nil
+
+	if gameID != 0 {
+		state, err := c.System.Store.GetGameByID(userID, gameID)
 		if err != nil {
-			return err
+			c.System.Logger.Printf("error loading game %d of user %s: %v", gameID, userID, err)
+		} else if state.Status == game.Paused || state.Status == game.InProgress {
+			state.Status = game.InProgress
+			c.GameState = state
+
+			err = c.saveGame()
+			if err != nil {
+				c.System.Logger.Printf("error saving game %d of user %s: %v", gameID, userID, err)
+			}
 		}
 	}
 
-	newGame := game.State{
-		WordToGuess: randomHero(),
-		CharsTried:  []string{},
-		Status:      game.InProgress,
+	c.System.Logger.Printf("user %s reconnected", userID)
+
+	resp := messages.SessionResp{
+		UserID: c.UserID,
+		Token:  c.Token,
+	}
+	if c.GameState != nil {
+		state := *c.GameState
+		resp.Game = &state
+	}
+
+	return c.respond(resp)
+}
+
+// logoutHandler saves the game in progress, invalidates the session token and ends
+// the session of the current user. The connection stays open and can log in again.
+func (c *controller) logoutHandler() error {
+	c.System.Logger.Printf("%s is logging out", c.UserID)
+
+	c.autosave()
+
+	if c.Token != "" {
+		c.System.Sessions.Revoke(c.Token)
 	}
-	saved, err := c.System.Store.SaveGame(c.UserID, newGame)
+
+	c.UserID = ""
+	c.Token = ""
+	c.GameState = nil
+
+	return c.respond(messages.SessionResp{})
+}
+
+// newGameHandler returns a new game with the given options, see System.newGame, and
+// pauses the previous game if it is in progress.
+func (c *controller) newGameHandler(options string) error {
+	c.System.Logger.Printf("%s is starting a new game", c.UserID)
+
+	state, err := c.System.newGame(c.UserID, options)
 	if err != nil {
-		return err
+		return c.respond(messages.GameStateResp{Error: toError(err)})
+	}
+
+	err = c.pauseGame()
+	if err != nil {
+		return c.respond(messages.GameStateResp{Error: toError(err)})
+	}
+
+	saved, err := c.System.Store.SaveGame(c.UserID, state)
+	if err != nil {
+		return c.respond(messages.GameStateResp{Error: toError(err)})
 	}
 
 	c.GameState = saved
-	c.GameState.Status = game.InProgress
 
-	return c.Encoder.Encode(messages.GameStateResp{State: *c.GameState})
+	return c.respond(messages.GameStateResp{State: *c.GameState})
 }
 
 // helpHandler returns the game rules and available commands to interact with
@@ -123,8 +600,9 @@ func (c *controller) newGameHandler() error {
 func (c *controller) helpHandler() error {
 	c.System.Logger.Printf("%s is requesting help", c.UserID)
 
-	return c.Encoder.Encode(messages.HelpResp{
-		Info: game.Rules,
+	return c.respond(messages.HelpResp{
+		Info:       game.Rules,
+		Categories: c.System.words().Categories(),
 	})
 }
 
@@ -133,126 +611,409 @@ func (c *controller) helpHandler() error {
 func (c *controller) listGamesHandler() error {
 	c.System.Logger.Printf("%s is listing games played", c.UserID)
 
-	if c.GameState != nil && c.GameState.Status == game.InProgress {
-		c.GameState.Status = game.Paused
-
-		saved, err := c.System.Store.SaveGame(c.UserID, *c.GameState)
-		if err != nil {
-			return err
-		}
-
-		c.GameState = saved
+	err := c.pauseGame()
+	if err != nil {
+		return c.respond(messages.ListGamesResp{Error: toError(err)})
 	}
 
 	games, err := c.System.Store.GetGamesByUser(c.UserID)
 	if err != nil {
-		return err
+		return c.respond(messages.ListGamesResp{Error: toError(err)})
 	}
 
-	return c.Encoder.Encode(messages.ListGamesResp{
+	return c.respond(messages.ListGamesResp{
 		Games: games,
 	})
 }
 
 // resumeGameHandler sets the game identified by the gameID as the current game.
-// It save the existing game if in progress.
+// It pauses the existing game if in progress.
 func (c *controller) resumeGameHandler(gameID string) error {
 	c.System.Logger.Printf("%s is resuming game %s", c.UserID, gameID)
 
 	id, err := strconv.Atoi(gameID)
 	if err != nil {
-		return err
+		return c.respond(messages.GameStateResp{
+			Error: messages.NewError(
+				messages.InvalidGameID,
+				fmt.Sprintf("%q is not a valid game id", gameID),
+			).WithDetail("game_id", gameID),
+		})
 	}
 
 	toResume, err := c.System.Store.GetGameByID(c.UserID, id)
 	if err != nil {
-		return c.Encoder.Encode(messages.GameStateResp{
-			Error: &messages.Error{Message: err.Error()},
+		return c.respond(messages.GameStateResp{
+			Error: toError(err).WithDetail("game_id", gameID),
 		})
 	}
 
+	if toResume.Status != game.Paused && toResume.Status != game.InProgress {
+		return c.respond(messages.GameStateResp{
+			Error: messages.NewError(
+				messages.GameFinished,
+				fmt.Sprintf("game %d is over and cannot be resumed", id),
+			).WithDetail("game_id", gameID).WithDetail("status", string(toResume.Status)),
+		})
+	}
+
+	err = c.pauseGame()
+	if err != nil {
+		return c.respond(messages.GameStateResp{Error: toError(err)})
+	}
+
 	toResume.Status = game.InProgress
 	c.GameState = toResume
 
-	return c.Encoder.Encode(messages.GameStateResp{State: *c.GameState})
+	err = c.saveGame()
+	if err != nil {
+		return c.respond(messages.GameStateResp{Error: toError(err)})
+	}
+
+	return c.respond(messages.GameStateResp{State: *c.GameState})
 }
 
-// guessHandler updates the characters guessed or the characters missed by comparing
-// the user guess against the secret word. The function ensures that if a characters
-// appears multiple times in the secrete word the count will be update accordingly.
-// If the user attempts to use a wrong charcater that had already been tried the character
-// will not be counted as a miss.
+// guessHandler tries the user guess against the secret word of the current game
+// and saves the result. The rules are implemented by game.State.Guess.
 func (c *controller) guessHandler(charGuessed string) error {
 	c.System.Logger.Printf("%s is guessing %s", c.UserID, charGuessed)
 
-	gameError := c.validateGameStatus()
+	return c.moveHandler(charGuessed, applyGuess)
+}
+
+// solveHandler tries the word sent by the user as the secret word of the current
+// game and saves the result. The rules are implemented by game.State.Solve.
+func (c *controller) solveHandler(word string) error {
+	c.System.Logger.Printf("%s is solving with %s", c.UserID, word)
+
+	return c.moveHandler(word, applySolve)
+}
+
+// hintHandler reveals a letter of the secret word of the current game, saves the
+// result and sends the letter to the user. The rules are implemented by
+// game.State.Hint.
+func (c *controller) hintHandler() error {
+	c.System.Logger.Printf("%s is asking for a hint", c.UserID)
+
+	err := c.reloadGame()
+	if err != nil {
+		return c.respond(messages.GameStateResp{
+			Error: toError(err),
+		})
+	}
+
+	gameError := validateGameStatus(c.GameState)
 	if gameError != nil {
-		return c.Encoder.Encode(messages.GameStateResp{
+		return c.respond(messages.GameStateResp{
 			Error: gameError,
 		})
 	}
 
-	for _, char := range charGuessed {
-		charStr := string(char)
+	letter, outcome, err := c.GameState.Hint()
+	if err != nil {
+		return c.respond(messages.GameStateResp{
+			Error: toError(err),
+		})
+	}
+
+	err = c.saveGame()
+	if err != nil {
+		return c.respond(messages.GameStateResp{
+			Error: toError(err),
+		})
+	}
 
-		if strings.Contains(c.GameState.WordToGuess, charStr) {
-			occurences := strings.Count(c.GameState.WordToGuess, charStr)
+	return c.respond(messages.GameStateResp{
+		State:   *c.GameState,
+		Outcome: outcome,
+		Hint:    letter,
+	})
+}
 
-			for occurences > 0 {
-				c.GameState.CharsGuessed = append(c.GameState.CharsGuessed, charStr)
-				occurences--
-			}
-		} else {
-			if !utils.Contains(c.GameState.CharsTried, charStr) {
-				c.GameState.CharsTried = append(c.GameState.CharsTried, charStr)
-			}
+// moveHandler applies the move described by value to the current game with apply,
+// saves the result and sends it to the user.
+func (c *controller) moveHandler(value string, apply moveFunc) error {
+	err := c.reloadGame()
+	if err != nil {
+		return c.respond(messages.GameStateResp{
+			Error: toError(err),
+		})
+	}
+
+	gameError := validateGameStatus(c.GameState)
+	if gameError != nil {
+		return c.respond(messages.GameStateResp{
+			Error: gameError,
+		})
+	}
+
+	outcome, gameError := apply(c.GameState, value)
+	if gameError != nil {
+		return c.respond(messages.GameStateResp{
+			Error: gameError,
+		})
+	}
+
+	err = c.saveGame()
+	if err != nil {
+		return c.respond(messages.GameStateResp{
+			Error: toError(err),
+		})
+	}
+
+	return c.respond(messages.GameStateResp{
+		State:   *c.GameState,
+		Outcome: outcome,
+	})
+}
+
+// gameOptions holds the options of a new game, all optional: the category of the
+// word to guess, the difficulty of the game, see game.ParseDifficulty, and the game
+// mode, see game.Modes.
+type gameOptions struct {
+	Category   string
+	Difficulty string
+	Mode       string
+}
+
+// newGame returns the state of a new game in progress for userID. options lists the
+// options of the game, see gameOptions, in any order.
+func (s System) newGame(userID, options string) (game.State, error) {
+	var opts gameOptions
+	for _, option := range strings.Fields(options) {
+		if _, ok := s.words().Pack(option); ok && opts.Category == "" {
+			opts.Category = option
+			continue
+		}
+
+		if isMode(option) && opts.Mode == "" {
+			opts.Mode = option
+			continue
+		}
+
+		if opts.Difficulty != "" {
+			return game.State{}, s.unknownCategory(option)
+		}
+
+		opts.Difficulty = option
+	}
+
+	// a misspelled category is taken for the difficulty
+	_, err := game.ParseDifficulty(opts.Difficulty)
+	if err != nil && opts.Category == "" {
+		return game.State{}, messages.NewError(messages.InvalidDifficulty,
+			fmt.Sprintf("%v, or a category among %s", err, strings.Join(s.words().Categories(), ", "))).
+			WithDetail("difficulty", opts.Difficulty)
+	}
+
+	return s.newGameWith(userID, opts)
+}
+
+// newGameWith returns the state of a new game in progress for userID with the given
+// options.
+func (s System) newGameWith(userID string, opts gameOptions) (game.State, error) {
+	pack, ok := s.words().Pack(opts.Category)
+	if !ok {
+		return game.State{}, s.unknownCategory(opts.Category)
+	}
+
+	if opts.Mode != "" && !isMode(opts.Mode) {
+		return game.State{}, messages.NewError(messages.MalformedRequest,
+			fmt.Sprintf("unknown mode %q. Available modes are %s", opts.Mode, strings.Join(game.Modes, ", "))).
+			WithDetail("mode", opts.Mode)
+	}
+
+	d, err := game.ParseDifficulty(opts.Difficulty)
+	if err != nil {
+		return game.State{}, messages.NewError(messages.InvalidDifficulty, err.Error()).
+			WithDetail("difficulty", opts.Difficulty)
+	}
+
+	played, err := s.played(userID)
+	if err != nil {
+		return game.State{}, err
+	}
+
+	source := s.source()
+	if opts.Mode == game.ModeAdaptive {
+		source, err = s.adapt(userID, pack, source)
+		if err != nil {
+			return game.State{}, err
+		}
+	}
+
+	state, err := pack.NewGame(d, source, played)
+	if err != nil {
+		return game.State{}, messages.NewError(messages.InvalidDifficulty, err.Error()).
+			WithDetail("difficulty", opts.Difficulty)
+	}
+
+	return state, nil
+}
+
+// unknownCategory returns the error reporting that category is not the category of a
+// pack.
+func (s System) unknownCategory(category string) *messages.Error {
+	return messages.NewError(messages.UnknownCategory,
+		fmt.Sprintf("unknown category %q. Available categories are %s", category, strings.Join(s.words().Categories(), ", "))).
+		WithDetail("category", category)
+}
+
+// isMode returns true if mode is one of game.Modes.
+func isMode(mode string) bool {
+	for _, m := range game.Modes {
+		if m == mode {
+			return true
 		}
 	}
 
-	c.GameState.Status = c.updateGameStatus()
+	return false
+}
+
+// adapt returns a source picking with source the words of pack whose difficulty
+// matches the recent win rate of userID, see words.Adapt. Words are scored with the
+// solve stats of the games of all users.
+func (s System) adapt(userID string, pack *words.Pack, source words.WordSource) (words.WordSource, error) {
+	games, err := s.Store.GetGamesByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.Store.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	var all []game.State
+	for _, u := range users {
+		userGames, err := s.Store.GetGamesByUser(u)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, userGames...)
+	}
+
+	return words.Adapt(source, pack.Scores(words.Stats(all)), words.RecentWinRate(games)), nil
+}
+
+// played returns the words of the games of userID from the least to the most
+// recently started, or nil if NoRepeat is not set.
+func (s System) played(userID string) ([]string, error) {
+	if !s.NoRepeat {
+		return nil, nil
+	}
 
-	return c.Encoder.Encode(messages.GameStateResp{
-		State: *c.GameState,
+	games, err := s.Store.GetGamesByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].GameID < games[j].GameID
 	})
+
+	played := make([]string, len(games))
+	for i, g := range games {
+		played[i] = g.WordToGuess
+	}
+
+	return played, nil
 }
 
-// validateGameStatus ensure the user can guess a character. Error messages are returned
-// if a user tries to guess a hero but the game status doesn't allow it. This can happen if
-// - the game hasn't started
-// - there is no game in progress (e.g. all games have been paused or have been finished)
-func (c controller) validateGameStatus() *messages.Error {
-	if c.GameState == nil || c.GameState.Status != game.InProgress {
-		return &messages.Error{Message: "you must start a new game or resume a paused game before guessing the hero"}
+// moveFunc applies a player move to a game in progress and returns its outcome.
+type moveFunc func(state *game.State, value string) (game.Outcome, *messages.Error)
+
+// applyGuess tries each character of guess in turn against state, since players can
+// try several characters at once, and returns the outcome of the last one tried.
+// Characters following the one that ends the game are ignored. Nothing is tried if
+// one of the characters is invalid.
+func applyGuess(state *game.State, guess string) (game.Outcome, *messages.Error) {
+	stripped := strings.Join(strings.Fields(guess), "")
+	if stripped == "" {
+		return "", messages.NewError(messages.InvalidGuess, "you must try at least one character").
+			WithDetail("value", guess)
 	}
 
-	return nil
+	letters := strings.Split(stripped, "")
+
+	for _, letter := range letters {
+		if !state.ValidGuess(letter) {
+			return "", messages.NewError(messages.InvalidGuess,
+				fmt.Sprintf("%q is not a letter of the %q alphabet of the game", letter, state.Alphabet.Name)).
+				WithDetail("value", guess).
+				WithDetail("alphabet", state.Alphabet.Name)
+		}
+	}
+
+	var outcome game.Outcome
+	for _, letter := range letters {
+		var err error
+
+		outcome, err = state.Guess(letter)
+		if err != nil {
+			return "", toError(err)
+		}
+
+		if state.Status != game.InProgress {
+			break
+		}
+	}
+
+	return outcome, nil
 }
 
-// updateGameStatus checks if the current game status should be set to game-over, win
-// or in-progress.
-func (c *controller) updateGameStatus() game.Status {
-	if len(c.GameState.CharsTried) >= game.MaxWrongChars {
-		return game.GameOver
+// applySolve tries word as the word to guess of state.
+func applySolve(state *game.State, word string) (game.Outcome, *messages.Error) {
+	outcome, err := state.Solve(word)
+	if err != nil {
+		return "", toError(err)
 	}
 
-	if len(c.GameState.CharsGuessed) >= len(c.GameState.WordToGuess) {
-		return game.Won
+	if outcome == game.OutcomeInvalid {
+		return "", messages.NewError(messages.InvalidGuess, "you must try a word").
+			WithDetail("value", word)
 	}
 
-	return game.InProgress
+	return outcome, nil
+}
+
+// validateGameStatus ensure the user can guess a character. Error messages are returned
+// if a user tries to guess a hero but the game status doesn't allow it. This can happen if
+// - the game hasn't started
+// - there is no game in progress (e.g. all games have been paused or have been finished)
+func validateGameStatus(state *game.State) *messages.Error {
+	if state == nil || state.Status != game.InProgress {
+		return toError(game.ErrorNotInProgress)
+	}
+
+	return nil
 }
 
 // handlePlayerAction calls the appropriate handle according to the command issued by
 // the player. If no handler is found an error will be returned.
 func (c *controller) handlePlayerAction(input messages.PlayerReq) error {
+	c.RequestID = input.ID
 
 	switch input.Action {
 
+	case game.Handshake:
+		return c.handshakeHandler(input.Version)
+
+	case game.Ping:
+		return c.pingHandler()
+
 	case game.Login:
 		return c.loginHandler(input.Value)
 
+	case game.Reconnect:
+		return c.reconnectHandler(input.Value)
+
+	case game.Logout:
+		return c.logoutHandler()
+
 	case game.NewGame:
-		return c.newGameHandler()
+		return c.newGameHandler(input.Value)
 
 	case game.Help:
 		return c.helpHandler()
@@ -266,18 +1027,30 @@ func (c *controller) handlePlayerAction(input messages.PlayerReq) error {
 	case game.Guess:
 		return c.guessHandler(input.Value)
 
+	case game.Solve:
+		return c.solveHandler(input.Value)
+
+	case game.Hint:
+		return c.hintHandler()
+
 	default:
-		return fmt.Errorf("unexpected error: action %v was not recognized", input.Action)
-	}
+		c.System.Logger.Printf("unknown action %q", input.Action)
 
-	return nil
+		return c.respond(messages.NewError(
+			messages.UnknownAction,
+			fmt.Sprintf("action %v was not recognized", input.Action),
+		).WithDetail("action", string(input.Action)))
+	}
 }
 
-// parseUserInput decodes and parses the incoming user request.
-func parseUserInput(conn io.Reader) (*messages.PlayerReq, error) {
+// parseUserInput decodes and parses a user request read from the session reader.
+// Requests containing unknown fields are rejected.
+func parseUserInput(raw []byte) (*messages.PlayerReq, error) {
 	var req messages.PlayerReq
 
-	d := json.NewDecoder(conn)
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.DisallowUnknownFields()
+
 	err := d.Decode(&req)
 	if err != nil {
 		return nil, err
@@ -287,3 +1060,74 @@ func parseUserInput(conn io.Reader) (*messages.PlayerReq, error) {
 
 	return &req, nil
 }
+
+// peerIdentity returns the common name of the verified client certificate of conn,
+// or an empty string if the client did not present one. WebSocket connections are
+// unwrapped to reach the underlying TLS connection. The TLS handshake fails with a
+// timeout error if the client does not complete it within timeout.
+func peerIdentity(conn net.Conn, timeout time.Duration) (string, error) {
+	switch c := conn.(type) {
+	case *tls.Conn:
+		err := c.SetDeadline(time.Now().Add(timeout))
+		if err != nil {
+			return "", err
+		}
+
+		err = c.Handshake()
+		if err != nil {
+			return "", err
+		}
+
+		err = c.SetDeadline(time.Time{})
+		if err != nil {
+			return "", err
+		}
+
+		return certs.PeerCommonName(c.ConnectionState()), nil
+
+	case interface{ UnderlyingConn() net.Conn }:
+		return peerIdentity(c.UnderlyingConn(), timeout)
+	}
+
+	return "", nil
+}
+
+// requestError converts an error raised while reading a request into a protocol error.
+func requestError(err error, maxSize int) *messages.Error {
+	if err == errRequestTooLarge {
+		return messages.NewError(messages.RequestTooLarge, err.Error()).
+			WithDetail("max_bytes", strconv.Itoa(maxSize))
+	}
+
+	return messages.NewError(messages.MalformedRequest, err.Error())
+}
+
+// toError converts err into a protocol error. Known store errors are mapped to their
+// error codes, anything else is reported as an internal error.
+func toError(err error) *messages.Error {
+	switch err {
+	case store.ErrorUserNotFound:
+		return messages.NewError(messages.UserNotFound, err.Error())
+
+	case store.ErrorGameNotFound:
+		return messages.NewError(messages.GameNotFound, err.Error())
+
+	case store.ErrorMissingGameID:
+		return messages.NewError(messages.InvalidGameID, err.Error())
+
+	case game.ErrorNotInProgress:
+		return messages.NewError(messages.NoActiveGame, err.Error())
+
+	case game.ErrorNoHintsLeft, game.ErrorLastLife:
+		return messages.NewError(messages.NoHintsLeft, err.Error())
+
+	case store.ErrorStaleGame:
+		return messages.NewError(messages.StaleGame, err.Error())
+	}
+
+	if respErr, ok := err.(*messages.Error); ok {
+		return respErr
+	}
+
+	return messages.NewError(messages.InternalError, err.Error())
+}
@@ -2,19 +2,71 @@ package handlers
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"log"
+	"net"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/Popcore/hangmango/pkg/certs"
 	"github.com/Popcore/hangmango/pkg/game"
 	"github.com/Popcore/hangmango/pkg/messages"
 	"github.com/Popcore/hangmango/pkg/store"
+	"github.com/Popcore/hangmango/pkg/words"
 )
 
+// decodeEnvelope reads the next envelope written by the controller and decodes
+// its payload into resp.
+func decodeEnvelope(r io.Reader, resp interface{}) (*messages.Envelope, error) {
+	var envelope messages.Envelope
+
+	err := json.NewDecoder(r).Decode(&envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &envelope, envelope.Decode(resp)
+}
+
+// connectSession starts a session of system over a pipe and completes the handshake
+// from its client end. The returned channel receives the error ending the session.
+func connectSession(t *testing.T, system System) (net.Conn, chan error) {
+	serverConn, clientConn := net.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewSession(system, serverConn)
+		serverConn.Close()
+	}()
+
+	go json.NewEncoder(clientConn).Encode(messages.PlayerReq{Action: game.Handshake, Version: messages.ProtocolVersion})
+
+	var hello messages.HandshakeResp
+	_, err := decodeEnvelope(clientConn, &hello)
+	assert.Nil(t, err)
+
+	return clientConn, done
+}
+
+// sendRequest sends req over conn and decodes the payload of the response into resp.
+func sendRequest(t *testing.T, conn net.Conn, req messages.PlayerReq, resp interface{}) *messages.Envelope {
+	go json.NewEncoder(conn).Encode(req)
+
+	envelope, err := decodeEnvelope(conn, resp)
+	assert.Nil(t, err)
+
+	return envelope
+}
+
 func TestLoginHandler(t *testing.T) {
 	buffer := bytes.NewBuffer([]byte{})
 
@@ -24,18 +76,62 @@ func TestLoginHandler(t *testing.T) {
 			Store:  store.NewMemStore(),
 		},
 		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
 	}
 
 	err := c.loginHandler("user-id")
 	assert.Nil(t, err)
 
 	resp := messages.HelpResp{}
-	err = json.NewDecoder(buffer).Decode(&resp)
+	_, err = decodeEnvelope(buffer, &resp)
 	assert.Nil(t, err)
 
 	assert.Equal(t, game.Rules, resp.Info)
 }
 
+func TestLoginHandlerSwitchUser(t *testing.T) {
+	system := System{
+		Logger:   log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:    store.NewMemStore(),
+		Sessions: NewSessions(time.Hour),
+	}
+
+	conn, done := connectSession(t, system)
+
+	var first messages.HelpResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Login, Value: "first-user"}, &first)
+
+	var state messages.GameStateResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.NewGame}, &state)
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Guess, Value: "q"}, &state)
+	gameID := state.State.GameID
+
+	var second messages.HelpResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Login, Value: "second-user"}, &second)
+	assert.Nil(t, second.Error)
+
+	// the game of the first user is paused and the second one has no game yet
+	saved, err := system.Store.GetGameByID("first-user", gameID)
+	assert.Nil(t, err)
+	assert.Equal(t, game.Paused, saved.Status)
+	assert.Equal(t, []string{"q"}, saved.CharsTried)
+
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Guess, Value: "x"}, &state)
+	if assert.NotNil(t, state.Error) {
+		assert.Equal(t, messages.NoActiveGame, state.Error.Code)
+	}
+
+	// the token of the first user is no longer valid
+	var session messages.SessionResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Reconnect, Value: first.Token}, &session)
+	if assert.NotNil(t, session.Error) {
+		assert.Equal(t, messages.InvalidToken, session.Error.Code)
+	}
+
+	conn.Close()
+	assert.Equal(t, io.EOF, <-done)
+}
+
 func TestNewGameHandler(t *testing.T) {
 	buffer := bytes.NewBuffer([]byte{})
 
@@ -45,24 +141,25 @@ func TestNewGameHandler(t *testing.T) {
 			Store:  store.NewMemStore(),
 		},
 		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
 	}
 
 	c.System.Store.SaveNewUser("user-id")
 	c.UserID = "user-id"
 
-	err := c.newGameHandler()
+	err := c.newGameHandler("")
 	assert.Nil(t, err)
 
 	var resp messages.GameStateResp
 
-	err = json.NewDecoder(buffer).Decode(&resp)
+	_, err = decodeEnvelope(buffer, &resp)
 	assert.Nil(t, err)
 
 	assert.Equal(t, game.InProgress, resp.State.Status)
 	assert.Equal(t, []string{}, resp.State.CharsTried)
 	assert.Contains(t, resp.State.WordToGuess, "_")
 
-	err = c.newGameHandler()
+	err = c.newGameHandler("")
 	assert.Nil(t, err)
 
 	games, err := c.System.Store.GetGamesByUser("user-id")
@@ -72,7 +169,7 @@ func TestNewGameHandler(t *testing.T) {
 
 }
 
-func TestHelpHandler(t *testing.T) {
+func TestNewGameHandlerDifficulty(t *testing.T) {
 	buffer := bytes.NewBuffer([]byte{})
 
 	c := controller{
@@ -81,59 +178,247 @@ func TestHelpHandler(t *testing.T) {
 			Store:  store.NewMemStore(),
 		},
 		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
 	}
 
 	c.System.Store.SaveNewUser("user-id")
 	c.UserID = "user-id"
 
-	err := c.helpHandler()
+	err := c.newGameHandler("hard")
 	assert.Nil(t, err)
 
-	var resp messages.HelpResp
+	var resp messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
 
-	err = json.NewDecoder(buffer).Decode(&resp)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, game.Hard, resp.State.Difficulty)
+	assert.True(t, len(c.GameState.WordToGuess) >= game.Hard.MinLength)
+
+	err = c.newGameHandler("custom:min=20")
 	assert.Nil(t, err)
 
-	assert.Equal(t, game.Rules, resp.Info)
+	var failed messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &failed)
+	assert.Nil(t, err)
+
+	if assert.NotNil(t, failed.Error) {
+		assert.Equal(t, messages.InvalidDifficulty, failed.Error.Code)
+	}
+
+	err = c.newGameHandler("impossible")
+	assert.Nil(t, err)
+
+	var unknown messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &unknown)
+	assert.Nil(t, err)
+
+	if assert.NotNil(t, unknown.Error) {
+		assert.Equal(t, messages.InvalidDifficulty, unknown.Error.Code)
+		assert.Equal(t, "impossible", unknown.Error.Details["difficulty"])
+	}
+
+	assert.Equal(t, game.InProgress, c.GameState.Status)
 }
 
-func TestResumeGameHandler(t *testing.T) {
+func TestNewGameHandlerCategory(t *testing.T) {
 	buffer := bytes.NewBuffer([]byte{})
 
 	c := controller{
 		System: System{
 			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
 			Store:  store.NewMemStore(),
+			Words: words.NewLibrary(&words.Pack{
+				Category: "fromages",
+				Alphabet: game.French,
+				Words:    []words.Word{{Text: "comté", Clue: "from the Jura"}},
+			}),
 		},
 		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
 	}
 
 	c.System.Store.SaveNewUser("user-id")
 	c.UserID = "user-id"
 
-	g, err := c.System.Store.SaveGame("user-id", game.State{
-		WordToGuess: "foo",
-		Status:      game.InProgress,
-	})
+	err := c.newGameHandler("easy fromages")
 	assert.Nil(t, err)
 
-	err = c.resumeGameHandler(strconv.Itoa(g.GameID))
+	var resp messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &resp)
 	assert.Nil(t, err)
 
-	var resp messages.GameStateResp
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, "fromages", resp.State.Category)
+	assert.Equal(t, game.Easy, resp.State.Difficulty)
+	assert.Equal(t, "_ o _ _ é ", resp.State.WordToGuess)
+	assert.Empty(t, resp.State.Clue)
 
-	err = json.NewDecoder(buffer).Decode(&resp)
+	err = c.hintHandler()
 	assert.Nil(t, err)
 
-	expected := g
-	expected.WordToGuess = "_ _ _ "
-	expected.Status = game.InProgress
+	var clue messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &clue)
+	assert.Nil(t, err)
+	assert.Equal(t, game.OutcomeClue, clue.Outcome)
+	assert.Equal(t, "from the Jura", clue.State.Clue)
 
-	assert.Equal(t, *expected, resp.State)
-	assert.Equal(t, c.GameState.GameID, g.GameID)
+	err = c.newGameHandler("villains")
+	assert.Nil(t, err)
+
+	var unknown messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &unknown)
+	assert.Nil(t, err)
+	if assert.NotNil(t, unknown.Error) {
+		assert.Contains(t, unknown.Error.Message, "fromages, heroes")
+	}
+
+	err = c.newGameHandler("hard hard")
+	assert.Nil(t, err)
+
+	var twice messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &twice)
+	assert.Nil(t, err)
+	if assert.NotNil(t, twice.Error) {
+		assert.Equal(t, messages.UnknownCategory, twice.Error.Code)
+		assert.Equal(t, "hard", twice.Error.Details["category"])
+	}
 }
 
-func TestGuessHandlerGameOver(t *testing.T) {
+func TestNewGameLoadedCategory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "words")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "Villains.txt"), []byte("# category: Cartoon Villains\njoker\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packs, err := words.LoadDir(dir)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	system := System{
+		Logger:   log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:    store.NewMemStore(),
+		Sessions: NewSessions(time.Hour),
+		Words:    words.NewLibrary(packs...),
+	}
+
+	conn, done := connectSession(t, system)
+
+	var login messages.HelpResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Login, Value: "user-id"}, &login)
+	assert.Contains(t, login.Categories, "cartoon-villains")
+
+	var state messages.GameStateResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.NewGame, Value: "Cartoon-Villains"}, &state)
+	assert.Nil(t, state.Error)
+	assert.Equal(t, "cartoon-villains", state.State.Category)
+
+	conn.Close()
+	assert.Equal(t, io.EOF, <-done)
+}
+
+func TestNewGameSource(t *testing.T) {
+	s := System{Source: words.NewSequential()}
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		state, err := s.newGame("user-id", "")
+		assert.Nil(t, err)
+		assert.Equal(t, words.SourceSequential, state.Source)
+		assert.Equal(t, int64(i), state.Seed)
+
+		got = append(got, state.WordToGuess)
+	}
+
+	assert.Equal(t, []string{"superman", "spiderman", "batman"}, got)
+}
+
+func TestNewGameNoRepeat(t *testing.T) {
+	s := System{
+		Store:    store.NewMemStore(),
+		NoRepeat: true,
+	}
+	s.Store.SaveNewUser("user-id")
+
+	seen := map[string]bool{}
+	var first []string
+	for range words.Heroes.Words {
+		state, err := s.newGame("user-id", "")
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		assert.False(t, seen[state.WordToGuess], state.WordToGuess)
+		seen[state.WordToGuess] = true
+		first = append(first, state.WordToGuess)
+
+		_, err = s.Store.SaveGame("user-id", state)
+		assert.Nil(t, err)
+	}
+
+	// once every hero was played they come back in the same order
+	for _, want := range first[:3] {
+		state, err := s.newGame("user-id", "")
+		assert.Nil(t, err)
+		assert.Equal(t, want, state.WordToGuess)
+
+		_, err = s.Store.SaveGame("user-id", state)
+		assert.Nil(t, err)
+	}
+
+	_, err := s.newGame("unknown", "")
+	assert.Equal(t, store.ErrorUserNotFound, err)
+}
+
+func TestNewGameAdaptive(t *testing.T) {
+	pack := &words.Pack{Category: "test", Alphabet: game.English}
+	for _, w := range []string{"banana", "bandana", "quiz", "cabana", "jazz", "mammal"} {
+		pack.Words = append(pack.Words, words.Word{Text: w})
+	}
+
+	s := System{
+		Store:  store.NewMemStore(),
+		Words:  words.NewLibrary(pack),
+		Source: words.NewSequential(),
+	}
+
+	for i := 0; i < words.RecentGames; i++ {
+		s.Store.SaveGame("winner", game.State{WordToGuess: "cabana", Status: game.Won})
+		s.Store.SaveGame("loser", game.State{WordToGuess: "cabana", Status: game.GameOver})
+	}
+
+	scores := pack.Scores(nil)
+
+	state, err := s.newGame("winner", "test adaptive")
+	assert.Nil(t, err)
+	assert.Equal(t, "adaptive:sequential", state.Source)
+	assert.Contains(t, []string{scores[0].Word.Text, scores[1].Word.Text}, state.WordToGuess)
+
+	state, err = s.newGame("loser", "adaptive easy test")
+	assert.Nil(t, err)
+	assert.Equal(t, game.Easy, state.Difficulty)
+	assert.Zero(t, state.Seed)
+	assert.Contains(t, []string{scores[4].Word.Text, scores[5].Word.Text}, state.WordToGuess)
+
+	state, err = s.newGame("loser", "test classic")
+	assert.Nil(t, err)
+	assert.Equal(t, words.SourceSequential, state.Source)
+
+	_, err = s.newGame("loser", "test adaptive adaptive")
+	assert.NotNil(t, err)
+
+	_, err = s.newGame("unknown", "test adaptive")
+	assert.Equal(t, store.ErrorUserNotFound, err)
+}
+
+func TestHelpHandler(t *testing.T) {
 	buffer := bytes.NewBuffer([]byte{})
 
 	c := controller{
@@ -142,36 +427,24 @@ func TestGuessHandlerGameOver(t *testing.T) {
 			Store:  store.NewMemStore(),
 		},
 		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
 	}
 
 	c.System.Store.SaveNewUser("user-id")
 	c.UserID = "user-id"
 
-	c.GameState = &game.State{
-		WordToGuess:  "foo",
-		Status:       game.InProgress,
-		CharsGuessed: []string{"f"},
-		CharsTried:   []string{"a", "b", "c", "d", "e"},
-	}
-
-	err := c.guessHandler("g")
+	err := c.helpHandler()
 	assert.Nil(t, err)
 
-	var resp messages.GameStateResp
-
-	err = json.NewDecoder(buffer).Decode(&resp)
-	assert.Nil(t, err)
-	assert.Equal(t, game.InProgress, resp.State.Status)
+	var resp messages.HelpResp
 
-	err = c.guessHandler("h")
+	_, err = decodeEnvelope(buffer, &resp)
 	assert.Nil(t, err)
 
-	err = json.NewDecoder(buffer).Decode(&resp)
-	assert.Nil(t, err)
-	assert.Equal(t, game.GameOver, resp.State.Status)
+	assert.Equal(t, game.Rules, resp.Info)
 }
 
-func TestGuessHandlerGameWon(t *testing.T) {
+func TestResumeGameHandler(t *testing.T) {
 	buffer := bytes.NewBuffer([]byte{})
 
 	c := controller{
@@ -180,48 +453,71 @@ func TestGuessHandlerGameWon(t *testing.T) {
 			Store:  store.NewMemStore(),
 		},
 		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
 	}
 
 	c.System.Store.SaveNewUser("user-id")
 	c.UserID = "user-id"
 
-	c.GameState = &game.State{
-		WordToGuess:  "foo",
-		Status:       game.InProgress,
-		CharsGuessed: []string{"f", "o"},
-		CharsTried:   []string{"a", "b", "c", "d", "e"},
-	}
+	g, err := c.System.Store.SaveGame("user-id", game.State{
+		WordToGuess: "foo",
+		Status:      game.InProgress,
+	})
+	assert.Nil(t, err)
 
-	err := c.guessHandler("o")
+	err = c.resumeGameHandler(strconv.Itoa(g.GameID))
 	assert.Nil(t, err)
 
 	var resp messages.GameStateResp
 
-	err = json.NewDecoder(buffer).Decode(&resp)
+	_, err = decodeEnvelope(buffer, &resp)
 	assert.Nil(t, err)
-	assert.Equal(t, game.Won, resp.State.Status)
+
+	expected := g
+	expected.WordToGuess = "_ _ _ "
+	expected.Status = game.InProgress
+	expected.Revision = 0 // not sent to players
+
+	assert.Equal(t, *expected, resp.State)
+	assert.Equal(t, c.GameState.GameID, g.GameID)
 }
 
-func TestValidateGameStatus(t *testing.T) {
+func TestResumeGameHandlerErrors(t *testing.T) {
 	testcases := []struct {
-		state    *game.State
+		gameID   string
 		expected *messages.Error
 	}{
 		{
-			state:    nil,
-			expected: &messages.Error{Message: "you must start a new game or resume a paused game before guessing the hero"},
+			gameID: "abc",
+			expected: &messages.Error{
+				Code:    messages.InvalidGameID,
+				Message: `"abc" is not a valid game id`,
+				Details: map[string]string{"game_id": "abc"},
+			},
 		},
 		{
-			state: &game.State{
-				Status: game.Paused,
+			gameID: "99",
+			expected: &messages.Error{
+				Code:    messages.GameNotFound,
+				Message: store.ErrorGameNotFound.Error(),
+				Details: map[string]string{"game_id": "99"},
 			},
-			expected: &messages.Error{Message: "you must start a new game or resume a paused game before guessing the hero"},
 		},
 		{
-			state: &game.State{
-				Status: game.GameOver,
+			gameID: "1",
+			expected: &messages.Error{
+				Code:    messages.GameFinished,
+				Message: "game 1 is over and cannot be resumed",
+				Details: map[string]string{"game_id": "1", "status": "won"},
+			},
+		},
+		{
+			gameID: "2",
+			expected: &messages.Error{
+				Code:    messages.GameFinished,
+				Message: "game 2 is over and cannot be resumed",
+				Details: map[string]string{"game_id": "2", "status": "game over"},
 			},
-			expected: &messages.Error{Message: "you must start a new game or resume a paused game before guessing the hero"},
 		},
 	}
 
@@ -233,50 +529,1069 @@ func TestValidateGameStatus(t *testing.T) {
 			Store:  store.NewMemStore(),
 		},
 		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
 	}
 
 	c.System.Store.SaveNewUser("user-id")
+	c.System.Store.SaveGame("user-id", game.State{WordToGuess: "foo", Status: game.Won})
+	c.System.Store.SaveGame("user-id", game.State{WordToGuess: "bar", Status: game.GameOver})
 	c.UserID = "user-id"
 
 	for _, testcase := range testcases {
-		c.GameState = testcase.state
-
-		err := c.guessHandler("o")
+		err := c.resumeGameHandler(testcase.gameID)
 		assert.Nil(t, err)
 
 		var resp messages.GameStateResp
-		err = json.NewDecoder(buffer).Decode(&resp)
+		_, err = decodeEnvelope(buffer, &resp)
 		assert.Nil(t, err)
 		assert.Equal(t, testcase.expected, resp.Error)
 	}
+
+	// finished games are left untouched
+	won, _ := c.System.Store.GetGameByID("user-id", 1)
+	assert.Equal(t, game.Won, won.Status)
+
+	lost, _ := c.System.Store.GetGameByID("user-id", 2)
+	assert.Equal(t, game.GameOver, lost.Status)
+	assert.Nil(t, c.GameState)
 }
 
-func TestParseUserInput(t *testing.T) {
-	testcases := []struct {
-		input    []byte
-		expected *messages.PlayerReq
-	}{
-		{
-			input: []byte(`{"action": "try", "value": "x"}`),
-			expected: &messages.PlayerReq{
-				Action: "try",
-				Value:  "x",
-			},
+func TestHandlePlayerActionUnknown(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
 		},
-		{
-			input: []byte(`{"action": "new"}`),
-			expected: &messages.PlayerReq{
-				Action: "new",
-				Value:  "",
-			},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	err := c.handlePlayerAction(messages.PlayerReq{ID: "1", Action: "dance"})
+	assert.Nil(t, err)
+
+	var resp messages.Error
+	envelope, err := decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, messages.ErrorType, envelope.Type)
+	assert.Equal(t, "1", envelope.RequestID)
+	assert.Equal(t, messages.UnknownAction, resp.Code)
+	assert.Equal(t, map[string]string{"action": "dance"}, resp.Details)
+}
+
+func TestListGamesHandlerUnknownUser(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
 		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
 	}
 
-	for _, testcase := range testcases {
-		reader := bytes.NewReader(testcase.input)
-		got, err := parseUserInput(reader)
+	err := c.listGamesHandler()
+	assert.Nil(t, err)
 
-		assert.Nil(t, err)
-		assert.Equal(t, testcase.expected, got)
+	var resp messages.ListGamesResp
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, messages.UserNotFound, resp.Error.Code)
+}
+
+func TestGuessHandlerGameOver(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	c.System.Store.SaveNewUser("user-id")
+	c.UserID = "user-id"
+
+	c.GameState = &game.State{
+		WordToGuess:  "foo",
+		Status:       game.InProgress,
+		CharsGuessed: []string{"f"},
+		CharsTried:   []string{"a", "b", "c", "d", "e"},
 	}
+
+	err := c.guessHandler("g")
+	assert.Nil(t, err)
+
+	var resp messages.GameStateResp
+
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, game.InProgress, resp.State.Status)
+
+	err = c.guessHandler("h")
+	assert.Nil(t, err)
+
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, game.GameOver, resp.State.Status)
+}
+
+func TestGuessHandlerInvalidLetter(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	c.System.Store.SaveNewUser("user-id")
+	c.UserID = "user-id"
+
+	saved, err := c.System.Store.SaveGame("user-id", game.New("foo", game.Normal, game.English))
+	assert.Nil(t, err)
+	c.GameState = saved
+
+	err = c.guessHandler("bq1")
+	assert.Nil(t, err)
+
+	var resp messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, &messages.Error{
+		Code:    messages.InvalidGuess,
+		Message: `"1" is not a letter of the "en" alphabet of the game`,
+		Details: map[string]string{"value": "bq1", "alphabet": "en"},
+	}, resp.Error)
+
+	assert.Empty(t, c.GameState.CharsTried)
+
+	err = c.guessHandler("ß")
+	assert.Nil(t, err)
+
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	if assert.NotNil(t, resp.Error) {
+		assert.Equal(t, `"ß" is not a letter of the "en" alphabet of the game`, resp.Error.Message)
+	}
+
+	err = c.guessHandler(" ")
+	assert.Nil(t, err)
+
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	if assert.NotNil(t, resp.Error) {
+		assert.Equal(t, "you must try at least one character", resp.Error.Message)
+	}
+
+	stored, err := c.System.Store.GetGameByID("user-id", saved.GameID)
+	assert.Nil(t, err)
+	assert.Empty(t, stored.CharsTried)
+}
+
+func TestGuessHandlerGameWon(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	c.System.Store.SaveNewUser("user-id")
+	c.UserID = "user-id"
+
+	c.GameState = &game.State{
+		WordToGuess:  "foo",
+		Status:       game.InProgress,
+		CharsGuessed: []string{"f"},
+		CharsTried:   []string{"a", "b", "c", "d", "e"},
+	}
+
+	err := c.guessHandler("o")
+	assert.Nil(t, err)
+
+	var resp messages.GameStateResp
+
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, game.Won, resp.State.Status)
+	assert.Equal(t, game.OutcomeWon, resp.Outcome)
+}
+
+func TestSolveHandler(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	c.System.Store.SaveNewUser("user-id")
+	c.UserID = "user-id"
+
+	saved, err := c.System.Store.SaveGame("user-id", game.New("batman", game.Normal, game.English))
+	assert.Nil(t, err)
+	c.GameState = saved
+
+	err = c.handlePlayerAction(messages.PlayerReq{Action: game.Solve, Value: "superman"})
+	assert.Nil(t, err)
+
+	var miss messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &miss)
+	assert.Nil(t, err)
+	assert.Equal(t, game.OutcomeMiss, miss.Outcome)
+	assert.Equal(t, []string{"superman"}, miss.State.WordsTried)
+
+	err = c.handlePlayerAction(messages.PlayerReq{Action: game.Solve, Value: "batman"})
+	assert.Nil(t, err)
+
+	var won messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &won)
+	assert.Nil(t, err)
+	assert.Equal(t, game.OutcomeWon, won.Outcome)
+	assert.Equal(t, game.Won, won.State.Status)
+
+	games, err := c.System.Store.GetGamesByUser("user-id")
+	assert.Nil(t, err)
+	if assert.Len(t, games, 1) {
+		assert.Equal(t, []string{"superman"}, games[0].WordsTried)
+		assert.Equal(t, game.WrongSolveCost, games[0].Misses())
+	}
+}
+
+func TestHintHandler(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	c.System.Store.SaveNewUser("user-id")
+	c.UserID = "user-id"
+
+	saved, err := c.System.Store.SaveGame("user-id", game.New("capitanplanet", game.Normal, game.English))
+	assert.Nil(t, err)
+	c.GameState = saved
+
+	err = c.hintHandler()
+	assert.Nil(t, err)
+
+	var hint messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &hint)
+	assert.Nil(t, err)
+	assert.Equal(t, game.OutcomeHint, hint.Outcome)
+	assert.Equal(t, "c", hint.Hint)
+	assert.Equal(t, 1, hint.State.HintsUsed)
+
+	err = c.newGameHandler("")
+	assert.Nil(t, err)
+	_, err = decodeEnvelope(buffer, &messages.GameStateResp{})
+	assert.Nil(t, err)
+
+	err = c.resumeGameHandler(strconv.Itoa(saved.GameID))
+	assert.Nil(t, err)
+
+	var resumed messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &resumed)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, resumed.State.HintsUsed)
+
+	err = c.hintHandler()
+	assert.Nil(t, err)
+
+	var failed messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &failed)
+	assert.Nil(t, err)
+	if assert.NotNil(t, failed.Error) {
+		assert.Equal(t, messages.NoHintsLeft, failed.Error.Code)
+	}
+}
+
+func TestGuessHandlerInvalidGuess(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	c.System.Store.SaveNewUser("user-id")
+	c.UserID = "user-id"
+	c.GameState = &game.State{
+		WordToGuess: "foo",
+		Status:      game.InProgress,
+	}
+
+	err := c.guessHandler("")
+	assert.Nil(t, err)
+
+	var resp messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	if assert.NotNil(t, resp.Error) {
+		assert.Equal(t, messages.InvalidGuess, resp.Error.Code)
+	}
+	assert.Empty(t, resp.Outcome)
+}
+
+func TestGuessHandlerRepeatedLetters(t *testing.T) {
+	testcases := []struct {
+		name    string
+		word    string
+		guesses []string
+		outcome game.Outcome
+		status  game.Status
+		guessed []string
+	}{
+		{
+			name:    "repeating a correct letter does not win",
+			word:    "superman",
+			guesses: []string{"s", "s", "s", "s", "s", "s", "s", "s"},
+			outcome: game.OutcomeRepeat,
+			status:  game.InProgress,
+			guessed: []string{"s"},
+		},
+		{
+			name:    "repeating a correct letter in a single try does not win",
+			word:    "superman",
+			guesses: []string{"ssssssss"},
+			outcome: game.OutcomeRepeat,
+			status:  game.InProgress,
+			guessed: []string{"s"},
+		},
+		{
+			name:    "repeating a letter that appears several times does not win",
+			word:    "aladdin",
+			guesses: []string{"a", "d", "a", "d", "a", "d", "i"},
+			outcome: game.OutcomeHit,
+			status:  game.InProgress,
+			guessed: []string{"a", "d", "i"},
+		},
+		{
+			name:    "spaces between the characters tried are ignored",
+			word:    "aladdin",
+			guesses: []string{"a l  d"},
+			outcome: game.OutcomeHit,
+			status:  game.InProgress,
+			guessed: []string{"a", "l", "d"},
+		},
+		{
+			name:    "revealing every distinct letter wins",
+			word:    "aladdin",
+			guesses: []string{"a", "l", "d", "i", "n"},
+			outcome: game.OutcomeWon,
+			status:  game.Won,
+			guessed: []string{"a", "l", "d", "i", "n"},
+		},
+		{
+			name:    "repeated letters in the winning try are ignored",
+			word:    "bob",
+			guesses: []string{"bbo"},
+			outcome: game.OutcomeWon,
+			status:  game.Won,
+			guessed: []string{"b", "o"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		buffer := bytes.NewBuffer([]byte{})
+
+		c := controller{
+			System: System{
+				Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+				Store:  store.NewMemStore(),
+			},
+			Encoder: json.NewEncoder(buffer),
+			Version: messages.ProtocolVersion,
+			UserID:  "user-id",
+		}
+
+		state := game.New(testcase.word, game.Normal, game.English)
+		c.GameState = &state
+
+		var resp messages.GameStateResp
+		for _, guess := range testcase.guesses {
+			err := c.guessHandler(guess)
+			assert.Nil(t, err, testcase.name)
+
+			resp = messages.GameStateResp{}
+			_, err = decodeEnvelope(buffer, &resp)
+			assert.Nil(t, err, testcase.name)
+			assert.Nil(t, resp.Error, testcase.name)
+		}
+
+		assert.Equal(t, testcase.outcome, resp.Outcome, testcase.name)
+		assert.Equal(t, testcase.status, resp.State.Status, testcase.name)
+		assert.Equal(t, testcase.guessed, c.GameState.CharsGuessed, testcase.name)
+		assert.Empty(t, c.GameState.CharsTried, testcase.name)
+	}
+}
+
+func TestValidateGameStatus(t *testing.T) {
+	testcases := []struct {
+		state    *game.State
+		expected *messages.Error
+	}{
+		{
+			state:    nil,
+			expected: &messages.Error{Code: messages.NoActiveGame, Message: "you must start a new game or resume a paused game before guessing the hero"},
+		},
+		{
+			state: &game.State{
+				Status: game.Paused,
+			},
+			expected: &messages.Error{Code: messages.NoActiveGame, Message: "you must start a new game or resume a paused game before guessing the hero"},
+		},
+		{
+			state: &game.State{
+				Status: game.GameOver,
+			},
+			expected: &messages.Error{Code: messages.NoActiveGame, Message: "you must start a new game or resume a paused game before guessing the hero"},
+		},
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	c.System.Store.SaveNewUser("user-id")
+	c.UserID = "user-id"
+
+	for _, testcase := range testcases {
+		c.GameState = testcase.state
+
+		err := c.guessHandler("o")
+		assert.Nil(t, err)
+
+		var resp messages.GameStateResp
+		_, err = decodeEnvelope(buffer, &resp)
+		assert.Nil(t, err)
+		assert.Equal(t, testcase.expected, resp.Error)
+	}
+}
+
+func TestHandlePlayerActionEnvelope(t *testing.T) {
+	testcases := []struct {
+		req          messages.PlayerReq
+		expectedType messages.Type
+	}{
+		{
+			req:          messages.PlayerReq{ID: "1", Action: game.Help},
+			expectedType: messages.HelpType,
+		},
+		{
+			req:          messages.PlayerReq{ID: "2", Action: game.NewGame},
+			expectedType: messages.GameStateType,
+		},
+		{
+			req:          messages.PlayerReq{ID: "3", Action: game.ListGames},
+			expectedType: messages.ListGamesType,
+		},
+		{
+			req:          messages.PlayerReq{Action: game.Guess, Value: "a"},
+			expectedType: messages.GameStateType,
+		},
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	c.System.Store.SaveNewUser("user-id")
+	c.UserID = "user-id"
+
+	for _, testcase := range testcases {
+		err := c.handlePlayerAction(testcase.req)
+		assert.Nil(t, err)
+
+		var payload json.RawMessage
+		envelope, err := decodeEnvelope(buffer, &payload)
+		assert.Nil(t, err)
+		assert.Equal(t, testcase.expectedType, envelope.Type)
+		assert.Equal(t, testcase.req.ID, envelope.RequestID)
+	}
+}
+
+func TestParseUserInput(t *testing.T) {
+	testcases := []struct {
+		input    []byte
+		expected *messages.PlayerReq
+	}{
+		{
+			input: []byte(`{"action": "try", "value": "x"}`),
+			expected: &messages.PlayerReq{
+				Action: "try",
+				Value:  "x",
+			},
+		},
+		{
+			input: []byte(`{"action": "new"}`),
+			expected: &messages.PlayerReq{
+				Action: "new",
+				Value:  "",
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		got, err := parseUserInput(testcase.input)
+
+		assert.Nil(t, err)
+		assert.Equal(t, testcase.expected, got)
+	}
+}
+
+func TestNewSessionPipelinedRequests(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	system := System{
+		Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:  store.NewMemStore(),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- NewSession(system, serverConn)
+		serverConn.Close()
+	}()
+
+	batch := []messages.PlayerReq{
+		{ID: "0", Action: game.Handshake, Version: messages.ProtocolVersion},
+		{ID: "1", Action: game.Login, Value: "user-id"},
+		{ID: "2", Action: game.NewGame},
+	}
+	for i, char := range "abcdefg" {
+		batch = append(batch, messages.PlayerReq{
+			ID:     strconv.Itoa(i + 3),
+			Action: game.Guess,
+			Value:  string(char),
+		})
+	}
+
+	// all the requests are written to the connection in a single write
+	var payload bytes.Buffer
+	encoder := json.NewEncoder(&payload)
+	for _, req := range batch {
+		encoder.Encode(req)
+	}
+
+	go clientConn.Write(payload.Bytes())
+
+	decoder := json.NewDecoder(clientConn)
+	for _, req := range batch {
+		var envelope messages.Envelope
+		err := decoder.Decode(&envelope)
+		assert.Nil(t, err)
+		assert.Equal(t, req.ID, envelope.RequestID)
+		assert.NotEqual(t, messages.ErrorType, envelope.Type)
+	}
+
+	clientConn.Close()
+	assert.Equal(t, io.EOF, <-done)
+}
+
+func TestNewSessionLegacyClient(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	system := System{
+		Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:  store.NewMemStore(),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- NewSession(system, serverConn)
+		serverConn.Close()
+	}()
+
+	encoder := json.NewEncoder(clientConn)
+	decoder := json.NewDecoder(clientConn)
+
+	// clients that never handshake receive bare responses
+	for _, req := range []messages.PlayerReq{
+		{Action: game.Login, Value: "user-id"},
+		{Action: game.NewGame},
+		{Action: game.Guess, Value: "a"},
+	} {
+		go encoder.Encode(req)
+
+		var resp map[string]json.RawMessage
+		assert.Nil(t, decoder.Decode(&resp))
+		assert.NotContains(t, resp, "payload")
+		assert.NotContains(t, resp, "type")
+
+		if req.Action == game.Guess {
+			assert.Contains(t, resp, "game")
+			assert.Contains(t, resp, "outcome")
+		}
+	}
+
+	// until they negotiate a version
+	go encoder.Encode(messages.PlayerReq{ID: "1", Action: game.Handshake, Version: messages.ProtocolVersion})
+
+	var hello messages.HandshakeResp
+	envelope, err := decodeEnvelope(clientConn, &hello)
+	assert.Nil(t, err)
+	assert.Equal(t, messages.HandshakeType, envelope.Type)
+	assert.Equal(t, messages.ProtocolVersion, hello.Version)
+
+	clientConn.Close()
+	assert.Equal(t, io.EOF, <-done)
+}
+
+func TestHandshakeHandler(t *testing.T) {
+	testcases := []struct {
+		version         int
+		expectedVersion int
+		expectedError   bool
+	}{
+		{
+			version:         messages.ProtocolVersion,
+			expectedVersion: messages.ProtocolVersion,
+		},
+		{
+			version:         messages.ProtocolVersion + 1,
+			expectedVersion: messages.ProtocolVersion,
+		},
+		{
+			version:         messages.MinProtocolVersion - 1,
+			expectedVersion: messages.ProtocolVersion,
+			expectedError:   true,
+		},
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	for _, testcase := range testcases {
+		err := c.handshakeHandler(testcase.version)
+		assert.Nil(t, err)
+
+		var resp messages.HandshakeResp
+		_, err = decodeEnvelope(buffer, &resp)
+		assert.Nil(t, err)
+		assert.Equal(t, testcase.expectedVersion, resp.Version)
+
+		if testcase.expectedError {
+			assert.NotNil(t, resp.Error)
+			continue
+		}
+
+		assert.Nil(t, resp.Error)
+		assert.Equal(t, game.Actions, resp.Actions)
+		assert.Equal(t, game.MaxWrongChars, resp.Limits.MaxWrongChars)
+	}
+}
+
+func TestParseUserInputErrors(t *testing.T) {
+	input := `{"action": "help", "value": "a {\"quoted\"} }"}
+not json at all
+{"action": "new", "unknown": true}
+{"action": "try", "value": "` + strings.Repeat("x", 64) + `"}
+{"action": "try"
+ "value": "y"}
+{"action": "list"}`
+
+	reader := newRequestReader(strings.NewReader(input), 48)
+	next := func() (*messages.PlayerReq, error) {
+		raw, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+
+		return parseUserInput(raw)
+	}
+
+	req, err := next()
+	assert.Nil(t, err)
+	assert.Equal(t, `a {"quoted"} }`, req.Value)
+
+	_, err = next()
+	assert.Equal(t, errNotAnObject, err)
+
+	_, err = next()
+	assert.NotNil(t, err)
+
+	_, err = next()
+	assert.Equal(t, errRequestTooLarge, err)
+
+	_, err = next()
+	assert.NotNil(t, err)
+
+	req, err = next()
+	assert.Nil(t, err)
+	assert.Equal(t, game.ListGames, req.Action)
+
+	_, err = next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestNewSessionRecovery(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	// a nil store makes the login handler panic
+	system := System{
+		Logger:         log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		MaxRequestSize: 64,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- NewSession(system, serverConn)
+		serverConn.Close()
+	}()
+
+	go clientConn.Write([]byte(`{"id": "0", "action": "hello", "version": 1}
+{"id": "1", "action": "help", "extra": 1}
+garbage
+{"id": "2", "action": "try", "value": "` + strings.Repeat("x", 64) + `"}
+{"id": "3", "action": "login", "value": "user-id"}
+{"id": "4", "action": "help"}
+`))
+
+	expected := []struct {
+		msgType messages.Type
+		code    messages.ErrorCode
+	}{
+		{msgType: messages.HandshakeType},
+		{msgType: messages.ErrorType, code: messages.MalformedRequest},
+		{msgType: messages.ErrorType, code: messages.MalformedRequest},
+		{msgType: messages.ErrorType, code: messages.RequestTooLarge},
+		{msgType: messages.ErrorType, code: messages.InternalError},
+		{msgType: messages.HelpType},
+	}
+
+	decoder := json.NewDecoder(clientConn)
+	for _, e := range expected {
+		var envelope messages.Envelope
+		err := decoder.Decode(&envelope)
+		assert.Nil(t, err)
+		assert.Equal(t, e.msgType, envelope.Type)
+
+		if e.msgType == messages.ErrorType {
+			var resp messages.Error
+			assert.Nil(t, envelope.Decode(&resp))
+			assert.Equal(t, e.code, resp.Code)
+		}
+	}
+
+	clientConn.Close()
+	assert.Equal(t, io.EOF, <-done)
+}
+
+func TestPingHandler(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	c := controller{
+		System:  System{Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags)},
+		Encoder: json.NewEncoder(serverConn),
+		Version: messages.ProtocolVersion,
+	}
+
+	go c.handlePlayerAction(messages.PlayerReq{ID: "1", Action: game.Ping})
+
+	var resp messages.PongResp
+	envelope, err := decodeEnvelope(clientConn, &resp)
+	assert.Nil(t, err)
+	assert.Equal(t, messages.PongType, envelope.Type)
+	assert.Equal(t, "1", envelope.RequestID)
+}
+
+func TestNewSessionIdleTimeout(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	system := System{
+		Logger:      log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:       store.NewMemStore(),
+		IdleTimeout: 50 * time.Millisecond,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewSession(system, serverConn)
+		serverConn.Close()
+	}()
+
+	encoder := json.NewEncoder(clientConn)
+	decoder := json.NewDecoder(clientConn)
+
+	for _, req := range []messages.PlayerReq{
+		{ID: "0", Action: game.Handshake, Version: messages.ProtocolVersion},
+		{ID: "1", Action: game.Login, Value: "user-id"},
+		{ID: "2", Action: game.NewGame},
+		{ID: "3", Action: game.Ping},
+	} {
+		go encoder.Encode(req)
+
+		var envelope messages.Envelope
+		assert.Nil(t, decoder.Decode(&envelope))
+		assert.Equal(t, req.ID, envelope.RequestID)
+	}
+
+	// the client stays silent until the session times out
+	select {
+	case err := <-done:
+		assert.True(t, IsTimeout(err))
+	case <-time.After(time.Second):
+		t.Fatal("the session did not time out")
+	}
+
+	// the game in progress was saved
+	games, err := system.Store.GetGamesByUser("user-id")
+	assert.Nil(t, err)
+	if assert.Len(t, games, 1) {
+		assert.Equal(t, game.Paused, games[0].Status)
+	}
+}
+
+func TestNewSessionHandshakeTimeout(t *testing.T) {
+	certPEM, keyPEM, err := certs.GenerateSelfSigned("hangmango", []string{"localhost"}, time.Hour)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	system := System{
+		Logger:           log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:            store.NewMemStore(),
+		HandshakeTimeout: 50 * time.Millisecond,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewSession(system, tls.Server(serverConn, &tls.Config{Certificates: []tls.Certificate{cert}}))
+		serverConn.Close()
+	}()
+
+	// the client connects but never starts the TLS handshake
+	select {
+	case err := <-done:
+		assert.True(t, IsTimeout(err), "%v", err)
+	case <-time.After(time.Second):
+		t.Fatal("the handshake did not time out")
+	}
+}
+
+func TestNewSessionWriteTimeout(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	system := System{
+		Logger:       log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:        store.NewMemStore(),
+		WriteTimeout: 50 * time.Millisecond,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewSession(system, serverConn)
+		serverConn.Close()
+	}()
+
+	// the response to help is never read
+	go json.NewEncoder(clientConn).Encode(messages.PlayerReq{ID: "1", Action: game.Help})
+
+	select {
+	case err := <-done:
+		assert.True(t, IsTimeout(err))
+	case <-time.After(time.Second):
+		t.Fatal("the session did not time out")
+	}
+}
+
+func TestReconnectHandler(t *testing.T) {
+	system := System{
+		Logger:   log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:    store.NewMemStore(),
+		Sessions: NewSessions(time.Hour),
+	}
+
+	first, firstDone := connectSession(t, system)
+
+	var login messages.HelpResp
+	sendRequest(t, first, messages.PlayerReq{Action: game.Login, Value: "user-id"}, &login)
+	if !assert.NotEmpty(t, login.Token) {
+		return
+	}
+
+	var state messages.GameStateResp
+	sendRequest(t, first, messages.PlayerReq{Action: game.NewGame}, &state)
+	sendRequest(t, first, messages.PlayerReq{Action: game.Guess, Value: "q"}, &state)
+	gameID := state.State.GameID
+
+	// the first connection is never closed by the client, the second one takes over
+	second, secondDone := connectSession(t, system)
+	defer second.Close()
+
+	var session messages.SessionResp
+	sendRequest(t, second, messages.PlayerReq{Action: game.Reconnect, Value: login.Token}, &session)
+	assert.Nil(t, session.Error)
+	assert.Equal(t, "user-id", session.UserID)
+	assert.Equal(t, login.Token, session.Token)
+	if assert.NotNil(t, session.Game) {
+		assert.Equal(t, gameID, session.Game.GameID)
+		assert.Equal(t, game.InProgress, session.Game.Status)
+		assert.Equal(t, []string{"q"}, session.Game.CharsTried)
+	}
+	assert.Equal(t, io.EOF, <-firstDone)
+
+	// the game is active again
+	sendRequest(t, second, messages.PlayerReq{Action: game.Guess, Value: "x"}, &state)
+	assert.Nil(t, state.Error)
+	assert.Equal(t, []string{"q", "x"}, state.State.CharsTried)
+
+	var logout messages.SessionResp
+	envelope := sendRequest(t, second, messages.PlayerReq{Action: game.Logout}, &logout)
+	assert.Equal(t, messages.SessionType, envelope.Type)
+	assert.Equal(t, messages.SessionResp{}, logout)
+
+	saved, err := system.Store.GetGameByID("user-id", gameID)
+	assert.Nil(t, err)
+	assert.Equal(t, game.Paused, saved.Status)
+
+	// the token is invalidated on logout
+	third, thirdDone := connectSession(t, system)
+	sendRequest(t, third, messages.PlayerReq{Action: game.Reconnect, Value: login.Token}, &session)
+	if assert.NotNil(t, session.Error) {
+		assert.Equal(t, messages.InvalidToken, session.Error.Code)
+	}
+
+	third.Close()
+	assert.Equal(t, io.EOF, <-thirdDone)
+
+	second.Close()
+	assert.Equal(t, io.EOF, <-secondDone)
+}
+
+func TestNewSessionAutosave(t *testing.T) {
+	system := System{
+		Logger:   log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:    store.NewMemStore(),
+		Sessions: NewSessions(time.Hour),
+	}
+
+	conn, done := connectSession(t, system)
+
+	var login messages.HelpResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Login, Value: "user-id"}, &login)
+
+	var first messages.GameStateResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.NewGame}, &first)
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Guess, Value: "q"}, &first)
+
+	// every move is saved as soon as it is made
+	saved, err := system.Store.GetGameByID("user-id", first.State.GameID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"q"}, saved.CharsTried)
+	assert.Equal(t, game.InProgress, saved.Status)
+
+	// resuming another game pauses and saves the outgoing one
+	var second messages.GameStateResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.NewGame}, &second)
+	sendRequest(t, conn, messages.PlayerReq{Action: game.ResumeGame, Value: strconv.Itoa(first.State.GameID)}, &first)
+
+	saved, err = system.Store.GetGameByID("user-id", second.State.GameID)
+	assert.Nil(t, err)
+	assert.Equal(t, game.Paused, saved.Status)
+
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Guess, Value: "x"}, &first)
+
+	// the connection is killed mid-game
+	conn.Close()
+	assert.Equal(t, io.EOF, <-done)
+
+	saved, err = system.Store.GetGameByID("user-id", first.State.GameID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"q", "x"}, saved.CharsTried)
+	assert.Equal(t, game.Paused, saved.Status)
+
+	// the progress is there after reconnecting
+	conn, done = connectSession(t, system)
+
+	var session messages.SessionResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Reconnect, Value: login.Token}, &session)
+	if assert.NotNil(t, session.Game) {
+		assert.Equal(t, first.State.GameID, session.Game.GameID)
+		assert.Equal(t, []string{"q", "x"}, session.Game.CharsTried)
+	}
+
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Guess, Value: "z"}, &first)
+	assert.Equal(t, []string{"q", "x", "z"}, first.State.CharsTried)
+
+	conn.Close()
+	assert.Equal(t, io.EOF, <-done)
+
+	// and after logging in again and resuming the game by id
+	conn, done = connectSession(t, system)
+	defer conn.Close()
+
+	sendRequest(t, conn, messages.PlayerReq{Action: game.Login, Value: "user-id"}, &login)
+
+	var resumed messages.GameStateResp
+	sendRequest(t, conn, messages.PlayerReq{Action: game.ResumeGame, Value: strconv.Itoa(first.State.GameID)}, &resumed)
+	assert.Nil(t, resumed.Error)
+	assert.Equal(t, []string{"q", "x", "z"}, resumed.State.CharsTried)
+	assert.Equal(t, game.InProgress, resumed.State.Status)
+
+	conn.Close()
+	assert.Equal(t, io.EOF, <-done)
 }
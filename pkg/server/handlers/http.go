@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Popcore/hangmango/pkg/certs"
+	"github.com/Popcore/hangmango/pkg/game"
+	"github.com/Popcore/hangmango/pkg/messages"
+)
+
+// httpAPI exposes the game operations as REST resources. It shares the System and
+// the game rules used by the TCP controller, so games created over HTTP can be
+// listed and resumed by TCP clients and vice versa.
+//
+// Routes:
+//
+//	GET  /help                               => game rules and commands
+//	GET  /users/{user}/games                 => list the games of a user
+//	POST /users/{user}/games                 => start a new game, ?category=&difficulty=&mode=
+//	GET  /users/{user}/games/{id}            => fetch a game
+//	POST /users/{user}/games/{id}/guesses    => try a character, body {"value": "a"}
+//	POST /users/{user}/games/{id}/solutions  => try a word, body {"value": "batman"}
+//	POST /users/{user}/games/{id}/hints      => reveal a letter for a life
+//
+// Clients that authenticated with a TLS certificate can only access the resources
+// of the user named by the certificate common name.
+type httpAPI struct {
+	System System
+}
+
+// NewHTTPHandler returns an http.Handler serving the REST API backed by system.
+func NewHTTPHandler(system System) http.Handler {
+	return &httpAPI{System: system}
+}
+
+// ServeHTTP routes the request to the handler of the requested resource.
+func (a *httpAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if len(parts) > 1 && parts[0] == "users" && r.TLS != nil {
+		certUserID := certs.PeerCommonName(*r.TLS)
+		if certUserID != "" && certUserID != parts[1] {
+			a.writeError(w, messages.NewError(
+				messages.Forbidden,
+				fmt.Sprintf("the client certificate of %s cannot access the games of %s", certUserID, parts[1]),
+			).WithDetail("user", parts[1]))
+			return
+		}
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0] == "help":
+		a.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: a.helpHandler,
+		})
+
+	case len(parts) == 3 && parts[0] == "users" && parts[2] == "games":
+		a.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				a.listGamesHandler(w, parts[1])
+			},
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+
+				a.newGameHandler(w, parts[1], gameOptions{
+					Category:   strings.ToLower(query.Get("category")),
+					Difficulty: strings.ToLower(query.Get("difficulty")),
+					Mode:       strings.ToLower(query.Get("mode")),
+				})
+			},
+		})
+
+	case len(parts) == 4 && parts[0] == "users" && parts[2] == "games":
+		a.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				a.getGameHandler(w, parts[1], parts[3])
+			},
+		})
+
+	case len(parts) == 5 && parts[0] == "users" && parts[2] == "games" && parts[4] == "guesses":
+		a.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+				a.moveHandler(w, r, parts[1], parts[3], applyGuess)
+			},
+		})
+
+	case len(parts) == 5 && parts[0] == "users" && parts[2] == "games" && parts[4] == "solutions":
+		a.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+				a.moveHandler(w, r, parts[1], parts[3], applySolve)
+			},
+		})
+
+	case len(parts) == 5 && parts[0] == "users" && parts[2] == "games" && parts[4] == "hints":
+		a.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+				a.hintHandler(w, parts[1], parts[3])
+			},
+		})
+
+	default:
+		a.writeError(w, messages.NewError(messages.NotFound, fmt.Sprintf("%s was not found", r.URL.Path)))
+	}
+}
+
+// route calls the handler registered for the request method or replies with a
+// method not allowed error.
+func (a *httpAPI) route(w http.ResponseWriter, r *http.Request, handlers map[string]http.HandlerFunc) {
+	handler, ok := handlers[r.Method]
+	if !ok {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		a.writeJSON(w, messages.NewError(messages.MalformedRequest, fmt.Sprintf("method %s is not allowed", r.Method)))
+		return
+	}
+
+	handler(w, r)
+}
+
+// helpHandler returns the game rules and available commands.
+func (a *httpAPI) helpHandler(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, messages.HelpResp{Info: game.Rules, Categories: a.System.words().Categories()})
+}
+
+// listGamesHandler returns the games played by userID.
+func (a *httpAPI) listGamesHandler(w http.ResponseWriter, userID string) {
+	a.System.Logger.Printf("%s is listing games played over http", userID)
+
+	games, err := a.System.Store.GetGamesByUser(userID)
+	if err != nil {
+		a.writeError(w, toError(err))
+		return
+	}
+
+	a.writeJSON(w, messages.ListGamesResp{Games: games})
+}
+
+// newGameHandler starts a new game with the given options, see gameOptions, for
+// userID. Unknown users are registered.
+func (a *httpAPI) newGameHandler(w http.ResponseWriter, userID string, options gameOptions) {
+	a.System.Logger.Printf("%s is starting a new game over http", userID)
+
+	err := a.System.Store.SaveNewUser(userID)
+	if err != nil {
+		a.writeError(w, toError(err))
+		return
+	}
+
+	state, err := a.System.newGameWith(userID, options)
+	if err != nil {
+		a.writeError(w, toError(err))
+		return
+	}
+
+	saved, err := a.System.Store.SaveGame(userID, state)
+	if err != nil {
+		a.writeError(w, toError(err))
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/users/%s/games/%d", userID, saved.GameID))
+	w.WriteHeader(http.StatusCreated)
+	a.writeJSON(w, messages.GameStateResp{State: *saved})
+}
+
+// getGameHandler returns the game identified by gameID and owned by userID.
+func (a *httpAPI) getGameHandler(w http.ResponseWriter, userID, gameID string) {
+	state, respErr := a.findGame(userID, gameID)
+	if respErr != nil {
+		a.writeError(w, respErr)
+		return
+	}
+
+	a.writeJSON(w, messages.GameStateResp{State: *state})
+}
+
+// moveHandler applies with apply the move sent in the request body, a character
+// or a word, to the game identified by gameID. Paused games are resumed, since over
+// HTTP each request addresses its game explicitly.
+func (a *httpAPI) moveHandler(w http.ResponseWriter, r *http.Request, userID, gameID string, apply moveFunc) {
+	var req messages.PlayerReq
+
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(a.System.maxRequestSize()))).Decode(&req)
+	if err != nil {
+		if errors.As(err, new(*http.MaxBytesError)) {
+			err = errRequestTooLarge
+		}
+
+		a.writeError(w, requestError(err, a.System.maxRequestSize()))
+		return
+	}
+
+	a.System.Logger.Printf("%s is playing %s over http", userID, req.Value)
+
+	state, respErr := a.findGame(userID, gameID)
+	if respErr != nil {
+		a.writeError(w, respErr)
+		return
+	}
+
+	if state.Status == game.Paused {
+		state.Status = game.InProgress
+	}
+
+	respErr = validateGameStatus(state)
+	if respErr != nil {
+		a.writeError(w, respErr)
+		return
+	}
+
+	outcome, respErr := apply(state, strings.ToLower(req.Value))
+	if respErr != nil {
+		a.writeError(w, respErr)
+		return
+	}
+
+	saved, err := a.System.Store.SaveGame(userID, *state)
+	if err != nil {
+		a.writeError(w, toError(err))
+		return
+	}
+
+	a.writeJSON(w, messages.GameStateResp{State: *saved, Outcome: outcome})
+}
+
+// hintHandler reveals a letter of the game identified by gameID. Paused games are
+// resumed, as they are by moveHandler.
+func (a *httpAPI) hintHandler(w http.ResponseWriter, userID, gameID string) {
+	a.System.Logger.Printf("%s is asking for a hint over http", userID)
+
+	state, respErr := a.findGame(userID, gameID)
+	if respErr != nil {
+		a.writeError(w, respErr)
+		return
+	}
+
+	if state.Status == game.Paused {
+		state.Status = game.InProgress
+	}
+
+	respErr = validateGameStatus(state)
+	if respErr != nil {
+		a.writeError(w, respErr)
+		return
+	}
+
+	letter, outcome, err := state.Hint()
+	if err != nil {
+		a.writeError(w, toError(err))
+		return
+	}
+
+	saved, err := a.System.Store.SaveGame(userID, *state)
+	if err != nil {
+		a.writeError(w, toError(err))
+		return
+	}
+
+	a.writeJSON(w, messages.GameStateResp{State: *saved, Outcome: outcome, Hint: letter})
+}
+
+// findGame returns the game identified by gameID and owned by userID.
+func (a *httpAPI) findGame(userID, gameID string) (*game.State, *messages.Error) {
+	id, err := strconv.Atoi(gameID)
+	if err != nil {
+		return nil, messages.NewError(
+			messages.InvalidGameID,
+			fmt.Sprintf("%q is not a valid game id", gameID),
+		).WithDetail("game_id", gameID)
+	}
+
+	state, err := a.System.Store.GetGameByID(userID, id)
+	if err != nil {
+		return nil, toError(err).WithDetail("game_id", gameID)
+	}
+
+	return state, nil
+}
+
+// writeError writes respErr with the HTTP status code matching its error code.
+func (a *httpAPI) writeError(w http.ResponseWriter, respErr *messages.Error) {
+	w.WriteHeader(httpStatus(respErr.Code))
+	a.writeJSON(w, respErr)
+}
+
+// writeJSON encodes v as the JSON response body.
+func (a *httpAPI) writeJSON(w http.ResponseWriter, v interface{}) {
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		a.System.Logger.Printf("error writing http response: %v", err)
+	}
+}
+
+// httpStatus maps protocol error codes to HTTP status codes.
+func httpStatus(code messages.ErrorCode) int {
+	switch code {
+	case messages.UserNotFound, messages.GameNotFound, messages.NotFound:
+		return http.StatusNotFound
+
+	case messages.Forbidden:
+		return http.StatusForbidden
+
+	case messages.RequestTooLarge:
+		return http.StatusRequestEntityTooLarge
+
+	case messages.MalformedRequest, messages.InvalidGameID, messages.InvalidGuess, messages.InvalidDifficulty, messages.UnknownCategory:
+		return http.StatusBadRequest
+
+	case messages.NoActiveGame, messages.NoHintsLeft, messages.GameFinished, messages.StaleGame:
+		return http.StatusConflict
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
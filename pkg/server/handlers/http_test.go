@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Popcore/hangmango/pkg/game"
+	"github.com/Popcore/hangmango/pkg/messages"
+	"github.com/Popcore/hangmango/pkg/store"
+	"github.com/Popcore/hangmango/pkg/words"
+)
+
+func TestHTTPAPI(t *testing.T) {
+	system := System{
+		Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:  store.NewMemStore(),
+	}
+
+	srv := httptest.NewServer(NewHTTPHandler(system))
+	defer srv.Close()
+
+	do := func(method, path, body string, expectedStatus int, resp interface{}) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		assert.Nil(t, err)
+
+		res, err := http.DefaultClient.Do(req)
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer res.Body.Close()
+
+		assert.Equal(t, expectedStatus, res.StatusCode, "%s %s", method, path)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(resp))
+	}
+
+	var help messages.HelpResp
+	do(http.MethodGet, "/help", "", http.StatusOK, &help)
+	assert.Equal(t, game.Rules, help.Info)
+
+	var respErr messages.Error
+	do(http.MethodGet, "/users/user-id/games", "", http.StatusNotFound, &respErr)
+	assert.Equal(t, messages.UserNotFound, respErr.Code)
+
+	var state messages.GameStateResp
+	do(http.MethodPost, "/users/user-id/games", "", http.StatusCreated, &state)
+	assert.Equal(t, 1, state.State.GameID)
+	assert.Equal(t, game.InProgress, state.State.Status)
+
+	stored, err := system.Store.GetGameByID("user-id", 1)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	do(http.MethodPost, "/users/user-id/games/1/guesses", `{"value": "`+stored.WordToGuess[:1]+`"}`, http.StatusOK, &state)
+	assert.NotContains(t, strings.Fields(state.State.WordToGuess)[0], "_")
+
+	do(http.MethodGet, "/users/user-id/games/1", "", http.StatusOK, &state)
+	assert.Equal(t, 1, state.State.GameID)
+
+	var list messages.ListGamesResp
+	do(http.MethodGet, "/users/user-id/games", "", http.StatusOK, &list)
+	assert.Len(t, list.Games, 1)
+
+	do(http.MethodGet, "/users/user-id/games/abc", "", http.StatusBadRequest, &respErr)
+	assert.Equal(t, messages.InvalidGameID, respErr.Code)
+
+	do(http.MethodGet, "/users/user-id/games/99", "", http.StatusNotFound, &respErr)
+	assert.Equal(t, messages.GameNotFound, respErr.Code)
+
+	do(http.MethodPost, "/users/user-id/games/1/guesses", `not json`, http.StatusBadRequest, &respErr)
+	assert.Equal(t, messages.MalformedRequest, respErr.Code)
+
+	do(http.MethodPost, "/users/user-id/games/1/guesses", `{"value": "`+strings.Repeat("a", DefaultMaxRequestSize)+`"}`, http.StatusRequestEntityTooLarge, &respErr)
+	assert.Equal(t, messages.RequestTooLarge, respErr.Code)
+
+	do(http.MethodPost, "/users/user-id/games?category=unknown", "", http.StatusBadRequest, &respErr)
+	assert.Equal(t, messages.UnknownCategory, respErr.Code)
+
+	do(http.MethodPost, "/users/user-id/games?category=Heroes&difficulty=unknown", "", http.StatusBadRequest, &respErr)
+	assert.Equal(t, messages.InvalidDifficulty, respErr.Code)
+
+	do(http.MethodPost, "/users/user-id/games?mode=unknown", "", http.StatusBadRequest, &respErr)
+	assert.Equal(t, messages.MalformedRequest, respErr.Code)
+
+	do(http.MethodPost, "/users/user-id/games?difficulty=easy&mode=classic", "", http.StatusCreated, &state)
+	assert.Equal(t, game.Easy, state.State.Difficulty)
+
+	do(http.MethodDelete, "/users/user-id/games", "", http.StatusMethodNotAllowed, &respErr)
+	assert.Equal(t, messages.MalformedRequest, respErr.Code)
+
+	do(http.MethodGet, "/unknown", "", http.StatusNotFound, &respErr)
+	assert.Equal(t, messages.NotFound, respErr.Code)
+}
+
+func TestHTTPAPIGuessFinishedGame(t *testing.T) {
+	system := System{
+		Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:  store.NewMemStore(),
+	}
+
+	system.Store.SaveGame("user-id", game.State{
+		WordToGuess:  "foo",
+		CharsGuessed: []string{"f", "o", "o"},
+		Status:       game.Won,
+	})
+
+	srv := httptest.NewServer(NewHTTPHandler(system))
+	defer srv.Close()
+
+	res, err := http.Post(srv.URL+"/users/user-id/games/1/guesses", "application/json", strings.NewReader(`{"value": "a"}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer res.Body.Close()
+
+	var respErr messages.Error
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(&respErr))
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, messages.NoActiveGame, respErr.Code)
+}
+
+func TestHTTPAPISolve(t *testing.T) {
+	system := System{
+		Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:  store.NewMemStore(),
+	}
+
+	system.Store.SaveGame("user-id", game.New("foo", game.Normal, game.English))
+
+	srv := httptest.NewServer(NewHTTPHandler(system))
+	defer srv.Close()
+
+	res, err := http.Post(srv.URL+"/users/user-id/games/1/solutions", "application/json", strings.NewReader(`{"value": "FOO"}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer res.Body.Close()
+
+	var resp messages.GameStateResp
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(&resp))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, game.OutcomeWon, resp.Outcome)
+	assert.Equal(t, game.Won, resp.State.Status)
+}
+
+func TestHTTPAPIMixedWithSession(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	c := controller{
+		System: System{
+			Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+			Store:  store.NewMemStore(),
+			Source: words.NewSequential(),
+		},
+		Encoder: json.NewEncoder(buffer),
+		Version: messages.ProtocolVersion,
+	}
+
+	c.System.Store.SaveNewUser("user-id")
+	c.UserID = "user-id"
+
+	// superman is open in the session
+	err := c.newGameHandler("")
+	assert.Nil(t, err)
+
+	var resp messages.GameStateResp
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+
+	srv := httptest.NewServer(NewHTTPHandler(c.System))
+	defer srv.Close()
+
+	res, err := http.Post(srv.URL+"/users/user-id/games/1/guesses", "application/json", strings.NewReader(`{"value": "b"}`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	err = c.guessHandler("s")
+	assert.Nil(t, err)
+
+	_, err = decodeEnvelope(buffer, &resp)
+	assert.Nil(t, err)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, []string{"b"}, resp.State.CharsTried)
+	assert.Equal(t, "s _ _ _ _ _ _ _ ", resp.State.WordToGuess)
+
+	res, err = http.Post(srv.URL+"/users/user-id/games/1/hints", "application/json", nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	err = c.pauseGame()
+	assert.Nil(t, err)
+
+	stored, err := c.System.Store.GetGameByID("user-id", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b"}, stored.CharsTried)
+	assert.Equal(t, []string{"s", "u"}, stored.CharsGuessed)
+	assert.Equal(t, 1, stored.HintsUsed)
+	assert.Equal(t, game.Paused, stored.Status)
+}
+
+func TestHTTPAPIClientCertificate(t *testing.T) {
+	system := System{
+		Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:  store.NewMemStore(),
+	}
+	handler := NewHTTPHandler(system)
+
+	alice := &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "alice"}}}},
+	}
+
+	testcases := []struct {
+		path     string
+		tls      *tls.ConnectionState
+		expected int
+	}{
+		{path: "/users/alice/games", tls: alice, expected: http.StatusCreated},
+		{path: "/users/bob/games", tls: alice, expected: http.StatusForbidden},
+		{path: "/users/bob/games", tls: &tls.ConnectionState{}, expected: http.StatusCreated},
+		{path: "/users/bob/games", expected: http.StatusCreated},
+	}
+
+	for _, tc := range testcases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		req.TLS = tc.tls
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, tc.expected, rec.Code, tc.path)
+
+		if tc.expected == http.StatusForbidden {
+			var resp messages.Error
+			assert.Nil(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, messages.Forbidden, resp.Code)
+		}
+	}
+
+	games, err := system.Store.GetGamesByUser("bob")
+	assert.Nil(t, err)
+	assert.Len(t, games, 2)
+}
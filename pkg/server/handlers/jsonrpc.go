@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Popcore/hangmango/pkg/game"
+	"github.com/Popcore/hangmango/pkg/messages"
+)
+
+// rpcVersion is the only JSON-RPC version supported.
+const rpcVersion = "2.0"
+
+// Error codes defined by the JSON-RPC 2.0 specification. Game errors are reported
+// with rpcServerError and carry the protocol error as data.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	rpcServerError    = -32000
+)
+
+// rpcNullID is the id used to answer requests whose id could not be read.
+const rpcNullID = "null"
+
+// rpcMethods maps the JSON-RPC methods to the player actions they perform.
+var rpcMethods = map[string]game.PlayerAction{
+	"session.hello":     game.Handshake,
+	"session.login":     game.Login,
+	"session.ping":      game.Ping,
+	"session.reconnect": game.Reconnect,
+	"session.logout":    game.Logout,
+	"game.help":         game.Help,
+	"game.new":          game.NewGame,
+	"game.try":          game.Guess,
+	"game.solve":        game.Solve,
+	"game.hint":         game.Hint,
+	"game.resume":       game.ResumeGame,
+	"games.list":        game.ListGames,
+}
+
+// rpcRequest is a JSON-RPC 2.0 request object. Requests without an id are
+// notifications and are not answered.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// rpcParams are the named parameters accepted by the JSON-RPC methods. Positional
+// parameters are mapped to Value.
+type rpcParams struct {
+	Value   string `json:"value"`
+	Version int    `json:"version"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response object.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// rpcError is a JSON-RPC 2.0 error object.
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    *messages.Error `json:"data,omitempty"`
+}
+
+// rpcSession holds the JSON-RPC state of a connection. Responses to the requests of
+// a batch are collected and sent together once the whole batch has been processed.
+type rpcSession struct {
+	Encoder  *json.Encoder
+	batching bool
+	batch    []rpcResponse
+}
+
+// isRPC returns true if raw is a JSON-RPC request or batch.
+func isRPC(raw []byte) bool {
+	if len(raw) > 0 && raw[0] == '[' {
+		return true
+	}
+
+	var probe struct {
+		JSONRPC *string `json:"jsonrpc"`
+	}
+
+	return json.Unmarshal(raw, &probe) == nil && probe.JSONRPC != nil
+}
+
+// handle processes a single JSON-RPC request or a batch of them.
+func (s *rpcSession) handle(c *controller, raw []byte) error {
+	if raw[0] != '[' {
+		return s.handleRequest(c, raw)
+	}
+
+	// any JSON array is a batch, so decoding only fails on invalid JSON
+	var batch []json.RawMessage
+	err := json.Unmarshal(raw, &batch)
+	if err != nil {
+		return s.parseError(messages.NewError(messages.MalformedRequest, err.Error()))
+	}
+
+	if len(batch) == 0 {
+		c.RequestID = rpcNullID
+		return c.respond(messages.NewError(messages.MalformedRequest, "empty batch"))
+	}
+
+	s.batching = true
+	for _, req := range batch {
+		err = s.handleRequest(c, req)
+		if err != nil {
+			break
+		}
+	}
+	s.batching = false
+
+	responses := s.batch
+	s.batch = nil
+
+	if err != nil || len(responses) == 0 {
+		return err
+	}
+
+	return s.Encoder.Encode(responses)
+}
+
+// handleRequest validates a JSON-RPC request and dispatches it to the handler of
+// the matching player action.
+func (s *rpcSession) handleRequest(c *controller, raw []byte) error {
+	var req rpcRequest
+
+	if !json.Valid(raw) {
+		return s.parseError(messages.NewError(messages.MalformedRequest, "invalid JSON"))
+	}
+
+	err := json.Unmarshal(raw, &req)
+	if err != nil {
+		c.RequestID = rpcNullID
+		return c.respond(messages.NewError(messages.MalformedRequest, err.Error()))
+	}
+
+	c.RequestID = string(req.ID)
+
+	if req.JSONRPC != rpcVersion || req.Method == "" {
+		if c.RequestID == "" {
+			c.RequestID = rpcNullID
+		}
+
+		return c.respond(messages.NewError(messages.MalformedRequest, "invalid JSON-RPC 2.0 request"))
+	}
+
+	action, ok := rpcMethods[req.Method]
+	if !ok {
+		return c.respond(messages.NewError(
+			messages.UnknownAction,
+			fmt.Sprintf("method %s was not found", req.Method),
+		).WithDetail("method", req.Method))
+	}
+
+	params, err := parseRPCParams(req.Params)
+	if err != nil {
+		return s.write(c.RequestID, rpcResponse{
+			Error: &rpcError{Code: rpcInvalidParams, Message: err.Error()},
+		})
+	}
+
+	return c.dispatch(messages.PlayerReq{
+		ID:      c.RequestID,
+		Action:  action,
+		Value:   strings.ToLower(params.Value),
+		Version: params.Version,
+	})
+}
+
+// respond converts resp into a JSON-RPC response to the request identified by
+// requestID. Responses to notifications are discarded.
+func (s *rpcSession) respond(requestID string, resp messages.Response) error {
+	if respErr := messages.ErrorOf(resp); respErr != nil {
+		return s.write(requestID, rpcResponse{Error: toRPCError(respErr)})
+	}
+
+	return s.write(requestID, rpcResponse{Result: resp})
+}
+
+// parseError answers a message that could not be read as JSON.
+func (s *rpcSession) parseError(respErr *messages.Error) error {
+	rpcErr := toRPCError(respErr)
+	rpcErr.Code = rpcParseError
+
+	return s.write(rpcNullID, rpcResponse{Error: rpcErr})
+}
+
+// write sends resp to the client, or adds it to the current batch.
+func (s *rpcSession) write(requestID string, resp rpcResponse) error {
+	if requestID == "" {
+		return nil
+	}
+
+	resp.JSONRPC = rpcVersion
+	resp.ID = json.RawMessage(requestID)
+
+	if s.batching {
+		s.batch = append(s.batch, resp)
+		return nil
+	}
+
+	return s.Encoder.Encode(resp)
+}
+
+// parseRPCParams reads named or positional parameters. The first positional
+// parameter is used as the request value.
+func parseRPCParams(raw json.RawMessage) (rpcParams, error) {
+	var params rpcParams
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return params, nil
+	}
+
+	if raw[0] == '[' {
+		var positional []string
+		err := json.Unmarshal(raw, &positional)
+		if err != nil {
+			return params, fmt.Errorf("positional params must be strings: %v", err)
+		}
+
+		if len(positional) > 0 {
+			params.Value = positional[0]
+		}
+
+		return params, nil
+	}
+
+	err := json.Unmarshal(raw, &params)
+	if err != nil {
+		return params, fmt.Errorf("invalid params: %v", err)
+	}
+
+	return params, nil
+}
+
+// toRPCError converts a protocol error into a JSON-RPC error object. The protocol
+// error is always attached as data so that clients can branch on its code.
+func toRPCError(respErr *messages.Error) *rpcError {
+	code := rpcServerError
+
+	switch respErr.Code {
+	case messages.MalformedRequest:
+		code = rpcInvalidRequest
+	case messages.UnknownAction:
+		code = rpcMethodNotFound
+	case messages.InternalError:
+		code = rpcInternalError
+	}
+
+	return &rpcError{
+		Code:    code,
+		Message: respErr.Message,
+		Data:    respErr,
+	}
+}
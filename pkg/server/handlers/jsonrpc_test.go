@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Popcore/hangmango/pkg/game"
+	"github.com/Popcore/hangmango/pkg/messages"
+	"github.com/Popcore/hangmango/pkg/store"
+)
+
+// testRPCResponse mirrors rpcResponse with a decodable result.
+type testRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func TestJSONRPCSession(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	system := System{
+		Logger: log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:  store.NewMemStore(),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- NewSession(system, serverConn)
+		serverConn.Close()
+	}()
+
+	go clientConn.Write([]byte(`{"jsonrpc": "2.0", "method": "session.login", "params": {"value": "user-id"}, "id": 1}
+[
+	{"jsonrpc": "2.0", "method": "game.new", "id": 2},
+	{"jsonrpc": "2.0", "method": "game.dance", "id": 3},
+	{"jsonrpc": "2.0", "method": "game.try", "params": ["a"], "id": "four"},
+	{"jsonrpc": "2.0", "method": "games.list"},
+	{"jsonrpc": "1.0", "method": "game.try", "id": 5}
+]
+{"jsonrpc": "2.0", "method": "game.resume", "params": {"value": "abc"}, "id": 6}
+{"jsonrpc": "2.0", "method": "game.try", "params": [1], "id": 7}
+{not json}
+[{"jsonrpc": "2.0", "method": "game.help", "id": 8}, not json]
+[1]
+`))
+
+	decoder := json.NewDecoder(clientConn)
+
+	var resp testRPCResponse
+	assert.Nil(t, decoder.Decode(&resp))
+	assert.Equal(t, rpcVersion, resp.JSONRPC)
+	assert.Equal(t, "1", string(resp.ID))
+	assert.Nil(t, resp.Error)
+
+	var help messages.HelpResp
+	assert.Nil(t, json.Unmarshal(resp.Result, &help))
+	assert.Equal(t, game.Rules, help.Info)
+
+	var batch []testRPCResponse
+	assert.Nil(t, decoder.Decode(&batch))
+	if assert.Len(t, batch, 4) {
+		assert.Equal(t, "2", string(batch[0].ID))
+		assert.Nil(t, batch[0].Error)
+
+		var state messages.GameStateResp
+		assert.Nil(t, json.Unmarshal(batch[0].Result, &state))
+		assert.Equal(t, game.InProgress, state.State.Status)
+
+		assert.Equal(t, "3", string(batch[1].ID))
+		assert.Equal(t, rpcMethodNotFound, batch[1].Error.Code)
+		assert.Equal(t, messages.UnknownAction, batch[1].Error.Data.Code)
+
+		assert.Equal(t, `"four"`, string(batch[2].ID))
+		assert.Nil(t, batch[2].Error)
+
+		assert.Equal(t, "5", string(batch[3].ID))
+		assert.Equal(t, rpcInvalidRequest, batch[3].Error.Code)
+	}
+
+	assert.Nil(t, decoder.Decode(&resp))
+	assert.Equal(t, "6", string(resp.ID))
+	assert.Equal(t, rpcServerError, resp.Error.Code)
+	assert.Equal(t, messages.InvalidGameID, resp.Error.Data.Code)
+
+	assert.Nil(t, decoder.Decode(&resp))
+	assert.Equal(t, "7", string(resp.ID))
+	assert.Equal(t, rpcInvalidParams, resp.Error.Code)
+
+	resp = testRPCResponse{}
+	assert.Nil(t, decoder.Decode(&resp))
+	assert.Equal(t, "null", string(resp.ID))
+	assert.Equal(t, rpcParseError, resp.Error.Code)
+
+	resp = testRPCResponse{}
+	assert.Nil(t, decoder.Decode(&resp))
+	assert.Equal(t, "null", string(resp.ID))
+	assert.Equal(t, rpcParseError, resp.Error.Code)
+
+	batch = nil
+	assert.Nil(t, decoder.Decode(&batch))
+	if assert.Len(t, batch, 1) {
+		assert.Equal(t, "null", string(batch[0].ID))
+		assert.Equal(t, rpcInvalidRequest, batch[0].Error.Code)
+	}
+
+	clientConn.Close()
+	assert.Equal(t, io.EOF, <-done)
+}
+
+func TestIsRPC(t *testing.T) {
+	testcases := []struct {
+		input    string
+		expected bool
+	}{
+		{input: `{"jsonrpc": "2.0", "method": "game.new", "id": 1}`, expected: true},
+		{input: `[{"jsonrpc": "2.0", "method": "game.new", "id": 1}]`, expected: true},
+		{input: `{"action": "new"}`, expected: false},
+	}
+
+	for _, testcase := range testcases {
+		assert.Equal(t, testcase.expected, isRPC([]byte(testcase.input)), testcase.input)
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	// DefaultMaxRequestSize is the maximum size in bytes of a single client request
+	// used when the System does not set its own limit.
+	DefaultMaxRequestSize = 4096
+)
+
+var (
+	errNotAnObject     = errors.New("request must be a JSON object or array")
+	errRequestTooLarge = errors.New("request exceeds the maximum allowed size")
+)
+
+// requestReader splits the stream of bytes sent by a client into JSON objects, or
+// arrays of objects such as JSON-RPC batches. It
+// keeps track of nesting and string literals so that the end of each object can be
+// found without decoding it, which allows the reader to cap the size of each request
+// and to resynchronise with the stream after a malformed or oversized one.
+type requestReader struct {
+	r       *bufio.Reader
+	maxSize int
+}
+
+// newRequestReader returns a requestReader that reads from r and rejects objects
+// bigger than maxSize bytes.
+func newRequestReader(r io.Reader, maxSize int) *requestReader {
+	return &requestReader{
+		r:       bufio.NewReader(r),
+		maxSize: maxSize,
+	}
+}
+
+// next returns the raw bytes of the next JSON object or array in the stream. Input
+// that does not start one is discarded up to the end of the line and reported with
+// errNotAnObject. Objects bigger than the maximum size are discarded entirely and
+// reported with errRequestTooLarge. In both cases the following call to next resumes
+// from the rest of the stream. io.EOF is returned when the stream ends between two
+// objects, io.ErrUnexpectedEOF when it ends in the middle of one.
+func (r *requestReader) next() ([]byte, error) {
+	first, err := r.skipWhitespace()
+	if err != nil {
+		return nil, err
+	}
+
+	if first != '{' && first != '[' {
+		_, err = r.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		return nil, errNotAnObject
+	}
+
+	var (
+		buf      = []byte{first}
+		depth    = 1
+		inString bool
+		escaped  bool
+		tooLarge bool
+	)
+
+	for depth > 0 {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			return nil, err
+		}
+
+		if !tooLarge {
+			buf = append(buf, b)
+			if len(buf) > r.maxSize {
+				tooLarge = true
+				buf = nil
+			}
+		}
+
+		switch {
+		case escaped:
+			escaped = false
+		case inString && b == '\\':
+			escaped = true
+		case b == '"':
+			inString = !inString
+		case inString:
+		case b == '{' || b == '[':
+			depth++
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+
+	if tooLarge {
+		return nil, errRequestTooLarge
+	}
+
+	return buf, nil
+}
+
+// peek discards leading whitespace and returns the next byte without consuming it.
+func (r *requestReader) peek() (byte, error) {
+	for {
+		b, err := r.r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.r.ReadByte()
+			continue
+		}
+
+		return b[0], nil
+	}
+}
+
+// nextLine returns the next line of the stream without its line terminator. Lines
+// longer than the maximum size are discarded and reported with errRequestTooLarge.
+// io.EOF is returned when the stream ends and there are no more lines.
+func (r *requestReader) nextLine() (string, error) {
+	var (
+		line     []byte
+		tooLarge bool
+	)
+
+	for {
+		chunk, err := r.r.ReadSlice('\n')
+		if !tooLarge {
+			line = append(line, chunk...)
+			if len(line) > r.maxSize {
+				tooLarge = true
+				line = nil
+			}
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if err != nil && (err != io.EOF || len(line) == 0 && !tooLarge) {
+			return "", err
+		}
+
+		if tooLarge {
+			return "", errRequestTooLarge
+		}
+
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
+
+// skipWhitespace discards whitespace and returns the first meaningful byte.
+func (r *requestReader) skipWhitespace() (byte, error) {
+	for {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		return b, nil
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSessionTTL is how long a session token remains valid after the connection
+	// using it is closed, when Sessions is created without a TTL.
+	DefaultSessionTTL = 30 * time.Minute
+
+	// takeoverTimeout is how long Claim waits for the connection holding a token to
+	// release it after being closed.
+	takeoverTimeout = 5 * time.Second
+)
+
+var (
+	ErrorInvalidToken = errors.New("session token is invalid or has expired")
+)
+
+// Sessions keeps track of the session tokens issued on login. A token lets a player
+// whose connection dropped reconnect as the same user and land back in the game that
+// was active when the connection was lost.
+type Sessions struct {
+	TTL time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]*sessionToken
+}
+
+// sessionToken is the state of an issued token. conn is the connection currently
+// using the token, nil once it has been released. released is closed when conn
+// releases the token.
+type sessionToken struct {
+	userID   string
+	gameID   int
+	expires  time.Time
+	conn     net.Conn
+	released chan struct{}
+}
+
+// NewSessions returns an empty token registry whose tokens expire ttl after their
+// release. DefaultSessionTTL is used if ttl is not set.
+func NewSessions(ttl time.Duration) *Sessions {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+
+	return &Sessions{
+		TTL:    ttl,
+		tokens: make(map[string]*sessionToken),
+	}
+}
+
+// Issue returns a new token for userID, held by conn. Expired tokens are discarded.
+func (s *Sessions) Issue(userID string, conn net.Conn) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, t := range s.tokens {
+		if t.expired(now) {
+			delete(s.tokens, k)
+		}
+	}
+
+	s.tokens[token] = &sessionToken{
+		userID:   userID,
+		conn:     conn,
+		released: make(chan struct{}),
+	}
+
+	return token, nil
+}
+
+// Claim hands token over to conn and returns the user it was issued to and the game
+// that was active when it was last released, 0 if none. If another connection still
+// holds the token, e.g. a half-open connection of a crashed client, that connection
+// is closed and Claim waits for it to release the token so that its game is saved
+// first.
+func (s *Sessions) Claim(token string, conn net.Conn) (string, int, error) {
+	for {
+		s.mu.Lock()
+
+		t, ok := s.tokens[token]
+		if !ok || t.expired(time.Now()) {
+			delete(s.tokens, token)
+			s.mu.Unlock()
+
+			return "", 0, ErrorInvalidToken
+		}
+
+		if t.conn == conn {
+			s.mu.Unlock()
+
+			return t.userID, t.gameID, nil
+		}
+
+		if t.conn == nil {
+			t.conn = conn
+			t.released = make(chan struct{})
+			s.mu.Unlock()
+
+			return t.userID, t.gameID, nil
+		}
+
+		holder, released := t.conn, t.released
+		s.mu.Unlock()
+
+		holder.Close()
+
+		select {
+		case <-released:
+		case <-time.After(takeoverTimeout):
+			s.Release(token, holder, 0)
+		}
+	}
+}
+
+// Release detaches conn from token and records gameID as the game to resume when
+// the token is claimed again. The token expires TTL after being released. Release
+// has no effect if conn does not hold token.
+func (s *Sessions) Release(token string, conn net.Conn, gameID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[token]
+	if !ok || t.conn != conn {
+		return
+	}
+
+	t.gameID = gameID
+	t.conn = nil
+	t.expires = time.Now().Add(s.TTL)
+	close(t.released)
+}
+
+// SetTTL changes the TTL of the tokens released from now on.
+func (s *Sessions) SetTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.TTL = ttl
+}
+
+// Revoke invalidates token.
+func (s *Sessions) Revoke(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[token]
+	if !ok {
+		return
+	}
+
+	if t.conn != nil {
+		close(t.released)
+	}
+	delete(s.tokens, token)
+}
+
+// expired returns true if the token has been released for longer than its TTL.
+// Tokens held by a connection never expire.
+func (t *sessionToken) expired(now time.Time) bool {
+	return t.conn == nil && now.After(t.expires)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	sessions := NewSessions(time.Hour)
+
+	first, _ := net.Pipe()
+	second, _ := net.Pipe()
+
+	token, err := sessions.Issue("user-id", first)
+	assert.Nil(t, err)
+	assert.Len(t, token, 32)
+
+	// the token is taken over from the connection still holding it
+	go func() {
+		first.Read(make([]byte, 1))
+		sessions.Release(token, first, 3)
+	}()
+
+	userID, gameID, err := sessions.Claim(token, second)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-id", userID)
+	assert.Equal(t, 3, gameID)
+
+	// releasing a token held by another connection has no effect
+	sessions.Release(token, first, 4)
+
+	sessions.Release(token, second, 5)
+	userID, gameID, err = sessions.Claim(token, first)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-id", userID)
+	assert.Equal(t, 5, gameID)
+
+	sessions.Revoke(token)
+	_, _, err = sessions.Claim(token, second)
+	assert.Equal(t, ErrorInvalidToken, err)
+
+	_, _, err = sessions.Claim("unknown", second)
+	assert.Equal(t, ErrorInvalidToken, err)
+}
+
+func TestSessionsExpiry(t *testing.T) {
+	sessions := NewSessions(10 * time.Millisecond)
+
+	conn, _ := net.Pipe()
+
+	token, err := sessions.Issue("user-id", conn)
+	assert.Nil(t, err)
+
+	// tokens held by a connection never expire
+	time.Sleep(20 * time.Millisecond)
+	_, _, err = sessions.Claim(token, conn)
+	assert.Nil(t, err)
+
+	sessions.Release(token, conn, 0)
+	time.Sleep(20 * time.Millisecond)
+
+	_, _, err = sessions.Claim(token, conn)
+	assert.Equal(t, ErrorInvalidToken, err)
+}
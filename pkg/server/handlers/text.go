@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Popcore/hangmango/pkg/client/drawing"
+	"github.com/Popcore/hangmango/pkg/game"
+	"github.com/Popcore/hangmango/pkg/messages"
+)
+
+// quitCommand closes a plain text session.
+const quitCommand = "quit"
+
+// textSession renders the responses of a plain text session as human readable text,
+// so that players can reach the server with nothing but netcat or telnet. Requests are
+// lines made of a command followed by its optional value, e.g. "try a" or "resume 3".
+type textSession struct {
+	Writer io.Writer
+}
+
+// isText returns true if the first byte sent by a client does not start a JSON
+// message, in which case the session speaks the plain text protocol.
+func isText(first byte) bool {
+	return first != '{' && first != '['
+}
+
+// handleTextIO reads commands line by line and processes them until the client
+// disconnects or sends the quit command.
+func (c *controller) handleTextIO() error {
+	for {
+		err := c.awaitRequest()
+		if err != nil {
+			return err
+		}
+
+		line, err := c.Reader.nextLine()
+		if err != nil {
+			if err == io.EOF || isClosed(err) {
+				c.System.Logger.Printf("user %s disconnected", c.UserID)
+				return io.EOF
+			}
+
+			if err != errRequestTooLarge {
+				return err
+			}
+
+			c.System.Logger.Printf("malformed request from user %s: %v", c.UserID, err)
+
+			err = c.requestError(requestError(err, c.System.maxRequestSize()))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		req, ok := parseTextCommand(line)
+		if !ok {
+			continue
+		}
+
+		if req.Action == quitCommand {
+			c.System.Logger.Printf("user %s quit", c.UserID)
+			return io.EOF
+		}
+
+		err = c.dispatch(req)
+		if err != nil {
+			if isClosed(err) {
+				c.System.Logger.Printf("user %s disconnected", c.UserID)
+				return io.EOF
+			}
+
+			return err
+		}
+	}
+}
+
+// parseTextCommand converts a line typed by the player into a request. It returns
+// false for blank lines.
+func parseTextCommand(line string) (messages.PlayerReq, bool) {
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) == 0 {
+		return messages.PlayerReq{}, false
+	}
+
+	return messages.PlayerReq{
+		Action: game.PlayerAction(fields[0]),
+		Value:  strings.Join(fields[1:], " "),
+	}, true
+}
+
+// respond writes resp to the player as text, followed by a prompt.
+func (s *textSession) respond(resp messages.Response) error {
+	var b strings.Builder
+
+	if respErr := messages.ErrorOf(resp); respErr != nil {
+		fmt.Fprintf(&b, "Error: %s (%s)\n", respErr.Message, respErr.Code)
+	} else {
+		switch r := resp.(type) {
+		case messages.HelpResp:
+			fmt.Fprintln(&b, r.Info)
+			drawing.Categories(&b, r.Categories)
+			fmt.Fprintf(&b, "\tlogin <name>      => sets your user name\n\t%s              => closes the connection\n", quitCommand)
+			if r.Token != "" {
+				fmt.Fprintf(&b, "Your session token is %s. Type '%s %s' to get back to your game after a disconnection\n", r.Token, game.Reconnect, r.Token)
+			}
+
+		case messages.GameStateResp:
+			drawing.State(&b, masked(r.State))
+			drawing.Outcome(&b, r.Outcome)
+			drawing.Hint(&b, r.State, r.Hint)
+
+		case messages.ListGamesResp:
+			games := make([]game.State, len(r.Games))
+			for i, g := range r.Games {
+				games[i] = masked(g)
+			}
+			drawing.Games(&b, games)
+
+		case messages.SessionResp:
+			if r.UserID == "" {
+				fmt.Fprintln(&b, "Logged out")
+				break
+			}
+
+			fmt.Fprintf(&b, "Welcome back %s\n", r.UserID)
+			if r.Game != nil {
+				drawing.State(&b, masked(*r.Game))
+			}
+
+		case messages.PongResp:
+			fmt.Fprintln(&b, "pong")
+
+		case messages.HandshakeResp:
+			fmt.Fprintf(&b, "Protocol version %d. Available commands: %v\n", r.Version, r.Actions)
+		}
+	}
+
+	fmt.Fprint(&b, "=> ")
+
+	_, err := io.WriteString(s.Writer, b.String())
+
+	return err
+}
+
+// masked returns a copy of state whose word only shows the characters guessed, as
+// JSON clients receive it.
+func masked(state game.State) game.State {
+	state.WordToGuess = state.MaskedWord()
+	if !state.ClueShown {
+		state.Clue = ""
+	}
+
+	return state
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Popcore/hangmango/pkg/client/drawing"
+	"github.com/Popcore/hangmango/pkg/game"
+	"github.com/Popcore/hangmango/pkg/messages"
+	"github.com/Popcore/hangmango/pkg/store"
+)
+
+func TestTextSession(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	system := System{
+		Logger:         log.New(ioutil.Discard, "event: ", log.LstdFlags),
+		Store:          store.NewMemStore(),
+		MaxRequestSize: 64,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewSession(system, serverConn)
+		serverConn.Close()
+	}()
+
+	go clientConn.Write([]byte("\r\nlogin Bob\r\n\r\nnew\ntry q\n" + strings.Repeat("x", 100) + "\nlist\nresume abc\ndance\nquit\nhelp\n"))
+
+	output, err := ioutil.ReadAll(clientConn)
+	assert.Nil(t, err)
+	assert.Equal(t, io.EOF, <-done)
+
+	got := string(output)
+	assert.Contains(t, got, game.Rules)
+	assert.Contains(t, got, "login <name>")
+	assert.Contains(t, got, "Guess the hero: _ ")
+	assert.Contains(t, got, drawing.Display[1])
+	assert.Contains(t, got, "Characters tried: q")
+	assert.Contains(t, got, "Error: request exceeds the maximum allowed size (request_too_large)")
+	assert.Contains(t, got, "Game ID: 1 * Hero: _ ")
+	assert.Contains(t, got, "(invalid_game_id)")
+	assert.Contains(t, got, "Error: action dance was not recognized (unknown_action)")
+
+	// help was sent after quit
+	assert.Equal(t, 1, strings.Count(got, game.Rules))
+
+	games, err := system.Store.GetGamesByUser("bob")
+	assert.Nil(t, err)
+	assert.Len(t, games, 1)
+}
+
+func TestParseTextCommand(t *testing.T) {
+	testcases := []struct {
+		line     string
+		expected messages.PlayerReq
+		ok       bool
+	}{
+		{line: "try a", expected: messages.PlayerReq{Action: game.Guess, Value: "a"}, ok: true},
+		{line: "  RESUME   3 ", expected: messages.PlayerReq{Action: game.ResumeGame, Value: "3"}, ok: true},
+		{line: "new", expected: messages.PlayerReq{Action: game.NewGame}, ok: true},
+		{line: "solve Spider  Man", expected: messages.PlayerReq{Action: game.Solve, Value: "spider man"}, ok: true},
+		{line: "   ", ok: false},
+	}
+
+	for _, testcase := range testcases {
+		got, ok := parseTextCommand(testcase.line)
+		assert.Equal(t, testcase.ok, ok)
+		assert.Equal(t, testcase.expected, got)
+	}
+}
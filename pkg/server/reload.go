@@ -0,0 +1,263 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Popcore/hangmango/pkg/server/handlers"
+	"github.com/Popcore/hangmango/pkg/words"
+)
+
+// Config holds the settings that can be reloaded while the server is running, read
+// from the JSON file at Server.ConfigFile. Durations are written as strings such as
+// "10m". Settings that are not set keep the value the server was started with.
+// Reloaded settings apply to the sessions started after the reload.
+type Config struct {
+	IdleTimeout    string `json:"idle_timeout,omitempty"`
+	WriteTimeout   string `json:"write_timeout,omitempty"`
+	SessionTTL     string `json:"session_ttl,omitempty"`
+	MaxRequestSize int    `json:"max_request_size,omitempty"`
+}
+
+// settings are the parsed values of a Config. Zero values are not set.
+type settings struct {
+	idleTimeout    time.Duration
+	writeTimeout   time.Duration
+	sessionTTL     time.Duration
+	maxRequestSize int
+}
+
+// reloadState holds the settings loaded by the last successful reload and the
+// fingerprint of the files as they were last checked. The embedded mutex ensures
+// protected concurrent access from the sessions and the reloads.
+type reloadState struct {
+	sync.RWMutex
+	settings    settings
+	fingerprint string
+
+	// startTTL is the session TTL the server was started with, restored when a
+	// config stops setting it.
+	startTTL time.Duration
+}
+
+// Reload loads the word packs stored in WordsDir and the settings stored in
+// ConfigFile, when they are set, and swaps them with the ones in use. Sessions are
+// not interrupted and games in progress keep their word. Nothing is swapped if
+// the packs or the settings are invalid, in which case the error is returned.
+func (s Server) Reload() error {
+	var packs []*words.Pack
+	if s.WordsDir != "" {
+		var err error
+
+		packs, err = words.LoadDir(s.WordsDir)
+		if err != nil {
+			return fmt.Errorf("error loading word packs: %v", err)
+		}
+	}
+
+	var loaded settings
+	if s.ConfigFile != "" {
+		var err error
+
+		loaded, err = loadConfig(s.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("error loading config %s: %v", s.ConfigFile, err)
+		}
+	}
+
+	fingerprint, err := s.fingerprint()
+	if err != nil {
+		return err
+	}
+
+	if s.WordsDir != "" {
+		s.System.Words.Replace(packs)
+	}
+
+	if s.reload == nil {
+		return nil
+	}
+
+	s.reload.Lock()
+	defer s.reload.Unlock()
+
+	s.reload.settings = loaded
+	s.reload.fingerprint = fingerprint
+
+	if s.System.Sessions != nil {
+		if s.reload.startTTL == 0 {
+			s.reload.startTTL = s.System.Sessions.TTL
+		}
+
+		ttl := loaded.sessionTTL
+		if ttl == 0 {
+			ttl = s.reload.startTTL
+		}
+
+		s.System.Sessions.SetTTL(ttl)
+	}
+
+	return nil
+}
+
+// loadConfig reads and validates the Config stored in the JSON file at path.
+func loadConfig(path string) (settings, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return settings{}, err
+	}
+
+	var c Config
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return settings{}, err
+	}
+
+	if c.MaxRequestSize < 0 {
+		return settings{}, fmt.Errorf("max_request_size cannot be negative")
+	}
+
+	loaded := settings{maxRequestSize: c.MaxRequestSize}
+
+	durations := []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"idle_timeout", c.IdleTimeout, &loaded.idleTimeout},
+		{"write_timeout", c.WriteTimeout, &loaded.writeTimeout},
+		{"session_ttl", c.SessionTTL, &loaded.sessionTTL},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+
+		*d.dst, err = time.ParseDuration(d.value)
+		if err != nil || *d.dst <= 0 {
+			return settings{}, fmt.Errorf("invalid %s %q", d.name, d.value)
+		}
+	}
+
+	return loaded, nil
+}
+
+// system returns the services and settings used by new sessions: System with the
+// settings loaded by the last reload.
+func (s Server) system() handlers.System {
+	system := s.System
+	if s.reload == nil {
+		return system
+	}
+
+	s.reload.RLock()
+	defer s.reload.RUnlock()
+
+	if s.reload.settings.idleTimeout > 0 {
+		system.IdleTimeout = s.reload.settings.idleTimeout
+	}
+	if s.reload.settings.writeTimeout > 0 {
+		system.WriteTimeout = s.reload.settings.writeTimeout
+	}
+	if s.reload.settings.maxRequestSize > 0 {
+		system.MaxRequestSize = s.reload.settings.maxRequestSize
+	}
+
+	return system
+}
+
+// watchReloads reloads the word packs and the config on SIGHUP and, if
+// ReloadInterval is set, whenever their files change. Failed reloads are logged and
+// leave the previous packs and settings in use.
+func (s Server) watchReloads() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	if s.ReloadInterval > 0 {
+		ticker := time.NewTicker(s.ReloadInterval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-hup:
+			s.Logger.Println("reloading on SIGHUP")
+
+		case <-tick:
+			if !s.changed() {
+				continue
+			}
+
+			s.Logger.Println("reloading changed word packs or config")
+		}
+
+		err := s.Reload()
+		if err != nil {
+			s.Logger.Printf("reload rejected, keeping the previous configuration: %v", err)
+			continue
+		}
+
+		s.Logger.Printf("reloaded categories %v", s.System.Words.Categories())
+	}
+}
+
+// changed returns true if the word pack and config files changed since they were
+// last checked or reloaded, so that an invalid change is only reported once.
+func (s Server) changed() bool {
+	fingerprint, err := s.fingerprint()
+	if err != nil {
+		fingerprint = err.Error()
+	}
+
+	s.reload.Lock()
+	defer s.reload.Unlock()
+
+	if fingerprint == s.reload.fingerprint {
+		return false
+	}
+
+	s.reload.fingerprint = fingerprint
+
+	return true
+}
+
+// fingerprint describes the names, sizes and modification times of the files in
+// WordsDir and of ConfigFile.
+func (s Server) fingerprint() (string, error) {
+	var infos []os.FileInfo
+
+	if s.WordsDir != "" {
+		files, err := ioutil.ReadDir(s.WordsDir)
+		if err != nil {
+			return "", err
+		}
+
+		infos = append(infos, files...)
+	}
+
+	if s.ConfigFile != "" {
+		info, err := os.Stat(s.ConfigFile)
+		if err != nil {
+			return "", err
+		}
+
+		infos = append(infos, info)
+	}
+
+	var fingerprint string
+	for _, info := range infos {
+		fingerprint += fmt.Sprintf("%s:%d:%d;", info.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return fingerprint, nil
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	wordsDir := filepath.Join(dir, "words")
+	assert.Nil(t, os.Mkdir(wordsDir, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(wordsDir, "villains.txt"), []byte("joker\n"), 0644))
+
+	config := filepath.Join(dir, "config.json")
+	assert.Nil(t, ioutil.WriteFile(config, []byte(`{"idle_timeout": "1m", "session_ttl": "2m"}`), 0644))
+
+	s := New("0", false)
+	s.WordsDir = wordsDir
+	s.ConfigFile = config
+	s.System.WriteTimeout = 5 * time.Second
+
+	assert.Nil(t, s.Reload())
+	assert.Equal(t, []string{"heroes", "villains"}, s.System.Words.Categories())
+	assert.Equal(t, time.Minute, s.system().IdleTimeout)
+	assert.Equal(t, 5*time.Second, s.system().WriteTimeout)
+	assert.Equal(t, 2*time.Minute, s.System.Sessions.TTL)
+	assert.False(t, s.changed())
+
+	// invalid packs and configs are rejected
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(wordsDir, "cheeses.txt"), []byte("crème\n"), 0644))
+	assert.True(t, s.changed())
+	assert.False(t, s.changed())
+	assert.NotNil(t, s.Reload())
+	assert.Equal(t, []string{"heroes", "villains"}, s.System.Words.Categories())
+
+	assert.Nil(t, os.Remove(filepath.Join(wordsDir, "cheeses.txt")))
+	assert.Nil(t, ioutil.WriteFile(config, []byte(`{"idle_timeout": "soon"}`), 0644))
+	assert.NotNil(t, s.Reload())
+	assert.Equal(t, time.Minute, s.system().IdleTimeout)
+
+	// settings that are no longer set are restored
+	assert.Nil(t, ioutil.WriteFile(config, []byte(`{}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(wordsDir, "villains.txt"), []byte("# category: baddies\njoker\n"), 0644))
+	assert.Nil(t, s.Reload())
+	assert.Equal(t, []string{"baddies", "heroes"}, s.System.Words.Categories())
+	assert.Equal(t, time.Duration(0), s.system().IdleTimeout)
+	assert.Equal(t, 30*time.Minute, s.System.Sessions.TTL)
+}
@@ -1,23 +1,52 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/Popcore/hangmango/pkg/server/handlers"
 	"github.com/Popcore/hangmango/pkg/store"
+	"github.com/Popcore/hangmango/pkg/websocket"
+	"github.com/Popcore/hangmango/pkg/words"
 )
 
-// Server is
+// DefaultSocketMode is the file mode of the Unix domain socket used when the server
+// does not set its own. Only the user running the server can connect.
+const DefaultSocketMode os.FileMode = 0600
+
+// Server is the game server. Players connect over raw TCP on Port and, when WSPort
+// is set, over WebSocket on WSPort. When HTTPPort is set the REST API is served on it.
+// All the TCP listeners are secured with TLSConfig when it is set.
+//
+// When SocketPath is set players on the same host can also connect to the Unix domain
+// socket created at that path. Access to the socket is controlled by its file mode,
+// SocketMode, rather than by TLS.
+//
+// The word packs stored in WordsDir and the settings stored in ConfigFile are loaded
+// on start and reloaded on SIGHUP or, if ReloadInterval is set, when their files
+// change. See Reload.
 type Server struct {
-	Port    string
-	Verbose bool
-	Logger  *log.Logger
-	System  handlers.System
+	Port           string
+	WSPort         string
+	HTTPPort       string
+	SocketPath     string
+	SocketMode     os.FileMode
+	WordsDir       string
+	ConfigFile     string
+	ReloadInterval time.Duration
+	Verbose        bool
+	Logger         *log.Logger
+	System         handlers.System
+	TLSConfig      *tls.Config
+
+	reload *reloadState
 }
 
 // New returns a fully configured tcp server instance that can be
@@ -27,8 +56,11 @@ func New(port string, verbose bool) *Server {
 
 	memStore := store.NewMemStore()
 	system := handlers.System{
-		Store:  memStore,
-		Logger: logger,
+		Store:    memStore,
+		Sessions: handlers.NewSessions(handlers.DefaultSessionTTL),
+		Words:    words.NewLibrary(),
+		Source:   words.NewUniform(),
+		Logger:   logger,
 	}
 
 	return &Server{
@@ -36,6 +68,7 @@ func New(port string, verbose bool) *Server {
 		Verbose: false,
 		Logger:  logger,
 		System:  system,
+		reload:  &reloadState{},
 	}
 }
 
@@ -50,37 +83,180 @@ func newLogger(verbose bool) *log.Logger {
 // Start listens and responds to incoming client connections.
 // Each connection will be managed in its own goroutine.
 func (s Server) Start() {
-	l, err := net.Listen("tcp", fmt.Sprintf(":%s", s.Port))
+	if s.WordsDir != "" || s.ConfigFile != "" {
+		err := s.Reload()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		go s.watchReloads()
+	}
+
+	if s.WSPort != "" {
+		wl, err := s.listen(s.WSPort)
+		if err != nil {
+			log.Fatalf("Error listening: %v", err)
+		}
+
+		log.Printf("websocket server listening at port %s", s.WSPort)
+
+		go func() {
+			log.Fatalf("Error serving websocket connections: %v", s.ServeWebSocket(wl))
+		}()
+	}
+
+	if s.HTTPPort != "" {
+		hl, err := s.listen(s.HTTPPort)
+		if err != nil {
+			log.Fatalf("Error listening: %v", err)
+		}
+
+		log.Printf("http api listening at port %s", s.HTTPPort)
+
+		go func() {
+			log.Fatalf("Error serving http requests: %v", s.ServeHTTPAPI(hl))
+		}()
+	}
+
+	if s.SocketPath != "" {
+		ul, err := s.listenUnix(s.SocketPath)
+		if err != nil {
+			log.Fatalf("Error listening: %v", err)
+		}
+
+		log.Printf("server listening at socket %s", s.SocketPath)
+
+		go func() {
+			log.Fatalf("Error accepting connection: %v", s.Serve(ul))
+		}()
+	}
+
+	l, err := s.listen(s.Port)
 	if err != nil {
 		log.Fatalf("Error listening: %v", err)
 	}
-	defer l.Close()
 
 	log.Printf("server listening at port %s", s.Port)
 
+	log.Fatalf("Error accepting connection: %v", s.Serve(l))
+}
+
+// listen returns a TCP listener bound to port, secured with TLS if the server has a
+// TLS configuration.
+func (s Server) listen(port string) (net.Listener, error) {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.TLSConfig != nil {
+		return tls.NewListener(l, s.TLSConfig), nil
+	}
+
+	return l, nil
+}
+
+// listenUnix returns a listener bound to the Unix domain socket at path, whose file
+// mode is set to SocketMode. A socket left behind by a previous run is replaced, any
+// other file at path is reported as an error. The socket file is removed when the
+// listener is closed.
+func (s Server) listenUnix(path string) (net.Listener, error) {
+	info, err := os.Lstat(path)
+	if err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := s.SocketMode
+	if mode == 0 {
+		mode = DefaultSocketMode
+	}
+
+	err = os.Chmod(path, mode)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Serve accepts incoming connections on the listener l and starts a new game
+// session for each of them. It always returns a non-nil error and closes l.
+func (s Server) Serve(l net.Listener) error {
+	defer l.Close()
+
 	for {
 		conn, err := l.Accept()
 		if err != nil {
-			log.Fatalf("Error accepting connection: %v", err)
+			return err
 		}
 
 		go s.handleConnection(conn)
 	}
 }
 
+// ServeWebSocket accepts HTTP connections on the listener l and upgrades them to
+// WebSocket. Each WebSocket connection carries the same JSON messages exchanged
+// over TCP and is managed by its own game session.
+func (s Server) ServeWebSocket(l net.Listener) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			s.Logger.Printf("websocket handshake failed: %v", err)
+			return
+		}
+
+		s.handleConnection(conn)
+	})
+
+	return http.Serve(l, mux)
+}
+
+// ServeHTTPAPI serves the REST API on the listener l. The API shares the store and
+// the game rules used by the TCP and WebSocket sessions, as well as their timeouts.
+func (s Server) ServeHTTPAPI(l net.Listener) error {
+	srv := &http.Server{
+		Handler:      handlers.NewHTTPHandler(s.System),
+		IdleTimeout:  s.System.IdleTimeout,
+		WriteTimeout: s.System.WriteTimeout,
+	}
+
+	return srv.Serve(l)
+}
+
 // handleConnection starts a new game session when a new clients connect to the
-// server.
+// server. Panics are recovered so that a misbehaving session cannot crash the server.
 func (s Server) handleConnection(conn net.Conn) {
+	defer conn.Close()
+	defer func() {
+		if r := recover(); r != nil {
+			s.Logger.Printf("Internal error: session panicked: %v", r)
+		}
+	}()
+
 	s.Logger.Println("new client connected")
 
-	err := handlers.NewSession(s.System, conn)
+	err := handlers.NewSession(s.system(), conn)
 	if err != nil {
 		if err == io.EOF {
 			s.Logger.Printf("Client disconnected")
+		} else if handlers.IsTimeout(err) {
+			s.Logger.Printf("Client timed out")
 		} else {
 			s.Logger.Printf("Internal error: %v", err)
 		}
 	}
-
-	defer conn.Close()
 }
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Popcore/hangmango/pkg/certs"
+	"github.com/Popcore/hangmango/pkg/game"
+	"github.com/Popcore/hangmango/pkg/messages"
+	"github.com/Popcore/hangmango/pkg/websocket"
+)
+
+func TestPlayOverWebSocket(t *testing.T) {
+	s := New("0", false)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go s.ServeWebSocket(l)
+	defer l.Close()
+
+	conn, err := websocket.Dial("ws://" + l.Addr().String() + "/")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	encoder := json.NewEncoder(conn)
+	decoder := json.NewDecoder(conn)
+
+	send := func(req messages.PlayerReq, resp messages.Response) {
+		err := encoder.Encode(req)
+		assert.Nil(t, err)
+
+		var envelope messages.Envelope
+		err = decoder.Decode(&envelope)
+		assert.Nil(t, err)
+		assert.Equal(t, req.ID, envelope.RequestID)
+		assert.Equal(t, resp.MessageType(), envelope.Type)
+		assert.Nil(t, envelope.Decode(resp))
+	}
+
+	var handshake messages.HandshakeResp
+	send(messages.PlayerReq{ID: "1", Action: game.Handshake, Version: messages.ProtocolVersion}, &handshake)
+	assert.Nil(t, handshake.Error)
+
+	var help messages.HelpResp
+	send(messages.PlayerReq{ID: "2", Action: game.Login, Value: "user-id"}, &help)
+	assert.Equal(t, game.Rules, help.Info)
+
+	var state messages.GameStateResp
+	send(messages.PlayerReq{ID: "3", Action: game.NewGame}, &state)
+	assert.Equal(t, game.InProgress, state.State.Status)
+
+	games, err := s.System.Store.GetGamesByUser("user-id")
+	if !assert.Nil(t, err) || !assert.Len(t, games, 1) {
+		return
+	}
+
+	tried := map[rune]bool{}
+	for _, char := range games[0].WordToGuess {
+		if tried[char] {
+			continue
+		}
+		tried[char] = true
+
+		send(messages.PlayerReq{ID: "guess", Action: game.Guess, Value: string(char)}, &state)
+		assert.Nil(t, state.Error)
+	}
+
+	assert.Equal(t, game.Won, state.State.Status)
+	assert.Empty(t, state.State.CharsTried)
+}
+
+func TestServeMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{}
+	for _, name := range []string{"server", "alice"} {
+		certPEM, keyPEM, err := certs.GenerateSelfSigned(name, []string{"localhost", "127.0.0.1"}, time.Hour)
+		assert.Nil(t, err)
+
+		files[name] = filepath.Join(dir, name+".pem")
+		files[name+"-key"] = filepath.Join(dir, name+"-key.pem")
+		assert.Nil(t, ioutil.WriteFile(files[name], certPEM, 0644))
+		assert.Nil(t, ioutil.WriteFile(files[name+"-key"], keyPEM, 0600))
+	}
+
+	s := New("0", false)
+	s.TLSConfig, err = certs.ServerConfig(files["server"], files["server-key"], files["alice"])
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go s.Serve(tls.NewListener(l, s.TLSConfig))
+	defer l.Close()
+
+	clientConfig, err := certs.ClientConfig(files["server"], files["alice"], files["alice-key"])
+	assert.Nil(t, err)
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), clientConfig)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	encoder := json.NewEncoder(conn)
+	decoder := json.NewDecoder(conn)
+
+	// the user name sent on login is replaced by the certificate common name
+	for _, req := range []messages.PlayerReq{
+		{ID: "0", Action: game.Handshake, Version: messages.ProtocolVersion},
+		{ID: "1", Action: game.Login, Value: "bob"},
+		{ID: "2", Action: game.NewGame},
+	} {
+		assert.Nil(t, encoder.Encode(req))
+
+		var envelope messages.Envelope
+		assert.Nil(t, decoder.Decode(&envelope))
+		assert.Equal(t, req.ID, envelope.RequestID)
+	}
+
+	games, err := s.System.Store.GetGamesByUser("alice")
+	assert.Nil(t, err)
+	assert.Len(t, games, 1)
+
+	_, err = s.System.Store.GetGamesByUser("bob")
+	assert.NotNil(t, err)
+
+	// clients without a certificate are rejected
+	anonymousConfig, err := certs.ClientConfig(files["server"], "", "")
+	assert.Nil(t, err)
+
+	anonymous, err := tls.Dial("tcp", l.Addr().String(), anonymousConfig)
+	if err == nil {
+		defer anonymous.Close()
+
+		err = anonymous.Handshake()
+		if err == nil {
+			_, err = anonymous.Read(make([]byte, 1))
+		}
+	}
+	assert.NotNil(t, err)
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hangmango.sock")
+
+	// sockets left behind by previous runs are replaced
+	stale, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s := New("0", false)
+	s.SocketMode = 0660
+
+	l, err := s.listenUnix(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	go s.Serve(l)
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// legacy clients that do not handshake receive bare responses
+	assert.Nil(t, json.NewEncoder(conn).Encode(messages.PlayerReq{ID: "1", Action: game.Help}))
+
+	var resp messages.HelpResp
+	assert.Nil(t, json.NewDecoder(conn).Decode(&resp))
+	assert.Equal(t, game.Rules, resp.Info)
+
+	l.Close()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// regular files are never removed
+	assert.Nil(t, ioutil.WriteFile(path, []byte("data"), 0644))
+	_, err = s.listenUnix(path)
+	assert.NotNil(t, err)
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/Popcore/hangmango/pkg/game"
@@ -11,6 +12,7 @@ var (
 	ErrorGameNotFound  = errors.New("game not found")
 	ErrorUserNotFound  = errors.New("user not found")
 	ErrorMissingGameID = errors.New("ensure game has a valid ID. 0 is not a valid value")
+	ErrorStaleGame     = errors.New("the game was changed by another session, try again")
 )
 
 // Storer defines the functionalies a data store must expose in order to
@@ -24,10 +26,14 @@ type Storer interface {
 	GetGameByID(userID string, gameID int) (*game.State, error)
 
 	GetGamesByUser(userID string) ([]game.State, error)
+
+	GetUsers() ([]string, error)
 }
 
 // memStore is the in-memory implementation of the Storer interface. The embedded
-// mutex ensures protected concurrent access to its underlying games map.
+// mutex ensures protected concurrent access to its underlying games map. Games are
+// copied in and out of the map, so that sessions moving on the same game never share
+// the slices of the stored one.
 type memStore struct {
 	sync.Mutex
 	games map[string]map[int]game.State
@@ -43,7 +49,9 @@ func NewMemStore() Storer {
 
 // SaveGame saves a new game or upserts an existing one. Internally SaveGame checks
 // if g contains a valid id, and if if doesn't a new game will be saved with a new
-// id assigned to it. If g contains the id the existing game will be updated.
+// id assigned to it. If g contains the id the existing game will be updated, unless
+// it was saved since g was loaded: ErrorStaleGame is returned if the revision of g
+// is not the stored one. The revision of the saved game is incremented.
 func (s *memStore) SaveGame(userID string, g game.State) (*game.State, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -52,17 +60,22 @@ func (s *memStore) SaveGame(userID string, g game.State) (*game.State, error) {
 		g.GameID = len(s.games[userID]) + 1
 	}
 
+	if stored, ok := s.games[userID][g.GameID]; ok && stored.Revision != g.Revision {
+		return nil, ErrorStaleGame
+	}
+	g.Revision++
+
 	// new user
 	games, ok := s.games[userID]
 	if !ok {
 		s.games[userID] = map[int]game.State{
-			g.GameID: g,
+			g.GameID: clone(g),
 		}
 
 		return &g, nil
 	}
 
-	games[g.GameID] = g
+	games[g.GameID] = clone(g)
 
 	return &g, nil
 }
@@ -93,6 +106,7 @@ func (s *memStore) GetGameByID(userID string, gameID int) (*game.State, error) {
 
 	for id, game := range games {
 		if id == gameID {
+			game = clone(game)
 			return &game, nil
 		}
 	}
@@ -114,8 +128,40 @@ func (s *memStore) GetGamesByUser(userID string) ([]game.State, error) {
 
 	// flatten the map
 	for _, game := range games {
-		gameSlice = append(gameSlice, game)
+		gameSlice = append(gameSlice, clone(game))
 	}
 
 	return gameSlice, nil
 }
+
+// GetUsers returns the sorted ids of the users of the store.
+func (s *memStore) GetUsers() ([]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	users := make([]string, 0, len(s.games))
+	for userID := range s.games {
+		users = append(users, userID)
+	}
+	sort.Strings(users)
+
+	return users, nil
+}
+
+// clone returns a copy of g that does not share the backing arrays of its slices.
+func clone(g game.State) game.State {
+	g.CharsGuessed = cloneStrings(g.CharsGuessed)
+	g.CharsTried = cloneStrings(g.CharsTried)
+	g.WordsTried = cloneStrings(g.WordsTried)
+
+	return g
+}
+
+// cloneStrings returns a copy of s, nil if s is nil.
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	return append(make([]string, 0, len(s)), s...)
+}
@@ -1,6 +1,8 @@
 package store
 
 import (
+	"runtime"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,6 +26,7 @@ func TestSaveGame(t *testing.T) {
 		GameID:       1,
 		WordToGuess:  "test-word",
 		CharsGuessed: []string{},
+		Revision:     1,
 	}
 
 	assert.Nil(t, err)
@@ -45,12 +48,83 @@ func TestSaveGame(t *testing.T) {
 		GameID:       1,
 		WordToGuess:  "test-word",
 		CharsGuessed: []string{"a"},
+		Revision:     1,
 	}
 
 	_, err = store.SaveGame("user-id", toUpdate)
 	assert.Nil(t, err)
+
+	toUpdate.Revision = 2
 	assert.Equal(t, store.games["user-id"][1], toUpdate)
 	assert.Len(t, store.games["user-id"], 2)
+
+	// stale upsert
+	stale := *saved
+	stale.CharsGuessed = []string{"b"}
+
+	_, err = store.SaveGame("user-id", stale)
+	assert.Equal(t, ErrorStaleGame, err)
+	assert.Equal(t, store.games["user-id"][1], toUpdate)
+}
+
+func TestSaveGameConcurrentMoves(t *testing.T) {
+	store := NewMemStore()
+
+	g := game.New("batman", game.Normal, game.English)
+	g.CharsTried = make([]string, 0, 1024)
+
+	created, err := store.SaveGame("user-id", g)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// the letter of each successful save, indexed by the revision it created
+	var mu sync.Mutex
+	moves := map[int]string{}
+
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, letter := range []string{"b", "x"} {
+		wg.Add(1)
+		go func(letter string) {
+			defer wg.Done()
+			<-start
+
+			for i := 0; i < 200; i++ {
+				loaded, err := store.GetGameByID("user-id", created.GameID)
+				if !assert.Nil(t, err) {
+					return
+				}
+
+				runtime.Gosched()
+				loaded.CharsTried = append(loaded.CharsTried, letter)
+
+				saved, err := store.SaveGame("user-id", *loaded)
+				if err == ErrorStaleGame {
+					continue
+				}
+				if !assert.Nil(t, err) {
+					return
+				}
+
+				mu.Lock()
+				moves[saved.Revision] = letter
+				mu.Unlock()
+			}
+		}(letter)
+	}
+	close(start)
+	wg.Wait()
+
+	stored, err := store.GetGameByID("user-id", created.GameID)
+	assert.Nil(t, err)
+
+	want := []string{}
+	for revision := created.Revision + 1; revision <= stored.Revision; revision++ {
+		want = append(want, moves[revision])
+	}
+	assert.Equal(t, want, stored.CharsTried)
 }
 
 func TestGetGameByID(t *testing.T) {
@@ -74,6 +148,7 @@ func TestGetGameByID(t *testing.T) {
 	assert.Equal(t, ErrorGameNotFound, err)
 
 	got, err := store.GetGameByID("user-id", 1)
+	toSave.Revision = 1
 	assert.Equal(t, toSave, *got)
 }
 
@@ -116,3 +191,20 @@ func TestSaveNewUser(t *testing.T) {
 
 	assert.Equal(t, store.games["user-id"], map[int]game.State{})
 }
+
+func TestGetUsers(t *testing.T) {
+	store := memStore{
+		games: make(map[string]map[int]game.State),
+	}
+
+	got, err := store.GetUsers()
+	assert.Nil(t, err)
+	assert.Empty(t, got)
+
+	store.SaveNewUser("user-2")
+	store.SaveGame("user-1", game.State{WordToGuess: "batman"})
+
+	got, err = store.GetUsers()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"user-1", "user-2"}, got)
+}
@@ -0,0 +1,445 @@
+// Package websocket implements the subset of the WebSocket protocol (RFC 6455)
+// required to carry the game JSON messages between browsers, mobile front-ends or
+// the Go client and the game server.
+//
+// A Conn satisfies the net.Conn interface: reads return the payload of the data
+// frames sent by the peer as a continuous stream and every write is sent as a
+// single text frame. Ping, pong and close frames are handled internally.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// acceptGUID is the magic value defined by RFC 6455 used to compute the
+	// Sec-WebSocket-Accept handshake header.
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// maxControlPayload is the maximum payload size of control frames.
+	maxControlPayload = 125
+)
+
+// opcodes of the frames defined by RFC 6455.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+var (
+	ErrBadHandshake  = errors.New("websocket: bad handshake")
+	ErrBadFrame      = errors.New("websocket: malformed frame")
+	ErrNotSupported  = errors.New("websocket: connection does not support hijacking")
+	ErrUnknownScheme = errors.New("websocket: url scheme must be ws or wss")
+)
+
+// Conn is a WebSocket connection established either by Upgrade or by Dial.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isClient bool
+
+	// read state of the data frame being consumed. fragmented is set while the
+	// continuation frames of a message are expected.
+	remaining  int64
+	masked     bool
+	mask       [4]byte
+	maskPos    int
+	fragmented bool
+	closed     bool
+
+	// wmu protects the writes of frames and closeSent, set once a close frame
+	// was sent since no frame can follow it.
+	wmu       sync.Mutex
+	closeSent bool
+}
+
+// Upgrade performs the server side of the WebSocket opening handshake and takes
+// over the underlying connection. The caller is responsible for closing it.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" {
+		http.Error(w, "websocket handshake expected", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing websocket key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, ErrNotSupported
+	}
+
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\n")
+	fmt.Fprintf(brw, "Upgrade: websocket\r\n")
+	fmt.Fprintf(brw, "Connection: Upgrade\r\n")
+	fmt.Fprintf(brw, "Sec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+
+	err = brw.Flush()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{
+		conn: conn,
+		br:   brw.Reader,
+	}, nil
+}
+
+// Dial opens a TCP connection to the WebSocket server at rawurl and performs the
+// client side of the opening handshake.
+func Dial(rawurl string) (*Conn, error) {
+	return DialTLS(rawurl, nil)
+}
+
+// DialTLS is like Dial but uses config to secure wss:// connections. The default
+// TLS configuration is used if config is nil.
+func DialTLS(rawurl string, config *tls.Config) (*Conn, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		conn, err = net.Dial("tcp", u.Host)
+	case "wss":
+		conn, err = tls.Dial("tcp", u.Host, config)
+	default:
+		return nil, ErrUnknownScheme
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := NewClient(conn, u)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// NewClient performs the client side of the opening handshake over an existing
+// connection, which allows callers to provide their own transport.
+func NewClient(conn net.Conn, u *url.URL) (*Conn, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+		Host: u.Host,
+	}
+
+	err = req.Write(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, ErrBadHandshake
+	}
+
+	return &Conn{
+		conn:     conn,
+		br:       br,
+		isClient: true,
+	}, nil
+}
+
+// Read reads the payload of the data frames sent by the peer. Control frames are
+// processed transparently. io.EOF is returned once the peer closes the connection.
+func (c *Conn) Read(p []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.closed {
+			return 0, io.EOF
+		}
+
+		err := c.nextFrame()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+
+	n, err := c.br.Read(p)
+	if c.masked {
+		for i := 0; i < n; i++ {
+			p[i] ^= c.mask[c.maskPos%4]
+			c.maskPos++
+		}
+	}
+	c.remaining -= int64(n)
+
+	return n, err
+}
+
+// nextFrame reads frame headers until it finds a data frame, whose payload will be
+// returned by the following reads. Control frames are answered as required. Servers
+// reject unmasked frames, which clients must never send (RFC 6455, section 5.1), and
+// both ends reject reserved bits, which no extension was negotiated for, fragmented
+// control frames and continuation frames that do not continue a message (section 5.4).
+func (c *Conn) nextFrame() error {
+	for {
+		var header [2]byte
+		_, err := io.ReadFull(c.br, header[:])
+		if err != nil {
+			return err
+		}
+
+		fin := header[0]&0x80 != 0
+		rsv := header[0] & 0x70
+		opcode := header[0] & 0x0F
+		masked := header[1]&0x80 != 0
+		length := int64(header[1] & 0x7F)
+
+		if rsv != 0 || !c.isClient && !masked {
+			return ErrBadFrame
+		}
+
+		switch length {
+		case 126:
+			var ext [2]byte
+			_, err = io.ReadFull(c.br, ext[:])
+			length = int64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			_, err = io.ReadFull(c.br, ext[:])
+			length = int64(binary.BigEndian.Uint64(ext[:]))
+		}
+		if err != nil {
+			return err
+		}
+		if length < 0 {
+			return ErrBadFrame
+		}
+
+		var mask [4]byte
+		if masked {
+			_, err = io.ReadFull(c.br, mask[:])
+			if err != nil {
+				return err
+			}
+		}
+
+		switch opcode {
+		case opContinuation, opText, opBinary:
+			if (opcode == opContinuation) != c.fragmented {
+				return ErrBadFrame
+			}
+
+			c.fragmented = !fin
+			c.remaining = length
+			c.masked = masked
+			c.mask = mask
+			c.maskPos = 0
+
+			if length == 0 {
+				continue
+			}
+
+			return nil
+
+		case opClose, opPing, opPong:
+			if !fin || length > maxControlPayload {
+				return ErrBadFrame
+			}
+
+			payload := make([]byte, length)
+			_, err = io.ReadFull(c.br, payload)
+			if err != nil {
+				return err
+			}
+			if masked {
+				for i := range payload {
+					payload[i] ^= mask[i%4]
+				}
+			}
+
+			switch opcode {
+			case opPing:
+				err = c.writeFrame(opPong, payload)
+				if err != nil {
+					return err
+				}
+
+			case opClose:
+				c.closed = true
+				c.writeFrame(opClose, nil)
+
+				return io.EOF
+			}
+
+		default:
+			return ErrBadFrame
+		}
+	}
+}
+
+// Write sends p to the peer as a single text frame.
+func (c *Conn) Write(p []byte) (int, error) {
+	err := c.writeFrame(opText, p)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// writeFrame sends a single final frame. Frames sent by clients are masked as
+// required by the protocol. Close frames are only sent once.
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if opcode == opClose {
+		if c.closeSent {
+			return nil
+		}
+		c.closeSent = true
+	}
+
+	frame := []byte{0x80 | opcode}
+
+	var maskBit byte
+	if c.isClient {
+		maskBit = 0x80
+	}
+
+	length := len(payload)
+	switch {
+	case length <= maxControlPayload:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(length))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(length))
+	}
+
+	if c.isClient {
+		var mask [4]byte
+		_, err := rand.Read(mask[:])
+		if err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_, err := c.conn.Write(frame)
+
+	return err
+}
+
+// Close sends a close frame to the peer, unless one was already sent in answer to the
+// close frame of the peer, and closes the underlying connection.
+func (c *Conn) Close() error {
+	c.writeFrame(opClose, nil)
+
+	return c.conn.Close()
+}
+
+// UnderlyingConn returns the connection carrying the WebSocket frames.
+func (c *Conn) UnderlyingConn() net.Conn { return c.conn }
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr { return c.conn.LocalAddr() }
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// SetDeadline sets the read and write deadlines of the underlying connection.
+func (c *Conn) SetDeadline(t time.Time) error { return c.conn.SetDeadline(t) }
+
+// SetReadDeadline sets the read deadline of the underlying connection.
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.conn.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the write deadline of the underlying connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+// acceptKey computes the Sec-WebSocket-Accept header value for key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains returns true if the comma separated values of header name contain
+// token. The comparison is case insensitive.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
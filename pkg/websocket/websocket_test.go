@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// example taken from RFC 6455, section 1.3
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestUpgradeRejectsPlainRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := Upgrade(w, r)
+		assert.Equal(t, ErrBadHandshake, err)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestDialAndEcho(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		io.Copy(conn, conn)
+	}))
+	defer srv.Close()
+
+	conn, err := Dial(strings.Replace(srv.URL, "http", "ws", 1))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	messages := []string{
+		"short message",
+		strings.Repeat("medium", 100),
+		strings.Repeat("long", 20000),
+	}
+
+	for _, msg := range messages {
+		_, err = conn.Write([]byte(msg))
+		assert.Nil(t, err)
+
+		got := make([]byte, len(msg))
+		_, err = io.ReadFull(conn, got)
+		assert.Nil(t, err)
+		assert.Equal(t, msg, string(got))
+	}
+}
+
+func TestServerRejectsUnmaskedFrames(t *testing.T) {
+	// an unmasked text frame carrying "hi"
+	c := &Conn{br: bufio.NewReader(bytes.NewReader([]byte{0x81, 0x02, 'h', 'i'}))}
+
+	_, err := c.Read(make([]byte, 2))
+	assert.Equal(t, ErrBadFrame, err)
+}
+
+// maskedFrame returns a frame whose first byte is b0 carrying payload, masked with a
+// zero key as clients send them.
+func maskedFrame(b0 byte, payload string) []byte {
+	return append([]byte{b0, 0x80 | byte(len(payload)), 0, 0, 0, 0}, payload...)
+}
+
+func TestServerRejectsBadFrames(t *testing.T) {
+	testcases := map[string][]byte{
+		"reserved bits":         maskedFrame(0x80|0x40|opText, "hi"),
+		"fragmented ping":       maskedFrame(opPing, "hi"),
+		"fragmented close":      maskedFrame(opClose, ""),
+		"unexpected continue":   maskedFrame(0x80|opContinuation, "hi"),
+		"interrupted fragments": append(maskedFrame(opText, "he"), maskedFrame(0x80|opText, "hi")...),
+	}
+
+	for name, frames := range testcases {
+		c := &Conn{br: bufio.NewReader(bytes.NewReader(frames))}
+
+		_, err := ioutil.ReadAll(c)
+		assert.Equal(t, ErrBadFrame, err, name)
+	}
+}
+
+func TestReadFragmentedMessage(t *testing.T) {
+	var frames []byte
+	frames = append(frames, maskedFrame(opText, "hel")...)
+	frames = append(frames, maskedFrame(opContinuation, "")...)
+	frames = append(frames, maskedFrame(0x80|opContinuation, "lo")...)
+	frames = append(frames, maskedFrame(0x80|opText, " world")...)
+
+	c := &Conn{br: bufio.NewReader(bytes.NewReader(frames))}
+
+	got, err := ioutil.ReadAll(c)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello world", string(got))
+}
+
+func TestCloseAfterPeerClose(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	c := &Conn{conn: serverConn, br: bufio.NewReader(serverConn)}
+
+	go clientConn.Write(maskedFrame(0x80|opClose, ""))
+
+	received := make(chan []byte)
+	go func() {
+		b, _ := ioutil.ReadAll(clientConn)
+		received <- b
+	}()
+
+	_, err := c.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+
+	assert.Nil(t, c.Close())
+	assert.Equal(t, []byte{0x80 | opClose, 0}, <-received)
+}
+
+func TestDialUnknownScheme(t *testing.T) {
+	_, err := Dial("http://localhost:9090/")
+	assert.Equal(t, ErrUnknownScheme, err)
+}
@@ -0,0 +1,32 @@
+package words
+
+import (
+	"github.com/Popcore/hangmango/pkg/game"
+)
+
+const (
+	// DefaultCategory is the category of the games started without one.
+	DefaultCategory = "heroes"
+)
+
+// Heroes is the default pack: a collection of cartoon heroes that will be used as
+// words to guess during a hangman game.
+var Heroes = &Pack{
+	Category: DefaultCategory,
+	Language: game.English.Name,
+	Alphabet: game.English,
+	Words: []Word{
+		{Text: "superman"},
+		{Text: "spiderman"},
+		{Text: "batman"},
+		{Text: "catwoman"},
+		{Text: "jocker"},
+		{Text: "wolverine"},
+		{Text: "mickeymouse"},
+		{Text: "donaldduck"},
+		{Text: "wonderwoman"},
+		{Text: "capitanplanet"},
+		{Text: "rickandmorty"},
+		{Text: "ericcartman"},
+	},
+}
@@ -0,0 +1,222 @@
+package words
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Popcore/hangmango/pkg/game"
+)
+
+// LoadDir loads the word packs stored in the .json and .txt files of dir. Other files
+// are ignored. See LoadFile for the format of the files.
+func LoadDir(dir string) ([]*Pack, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var packs []*Pack
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || ext != ".json" && ext != ".txt" {
+			continue
+		}
+
+		p, err := LoadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		packs = append(packs, p)
+	}
+
+	return packs, nil
+}
+
+// LoadFile loads the word pack stored in the file at path. JSON files hold a Pack,
+// whose words are either plain strings or objects with a word, a clue, a difficulty
+// and a weight:
+//
+//	{
+//	  "category": "fromages",
+//	  "language": "fr",
+//	  "words": ["brie", {"word": "camembert", "clue": "made in Normandy", "difficulty": "easy"}]
+//	}
+//
+// Text files hold a word per line, optionally followed by its clue, difficulty and
+// weight separated by "|". Lines starting with "#" are comments, except for the
+// "# category:", "# language:", "# letters:" and "# fold_accents:" headers:
+//
+//	# category: fromages
+//	# language: fr
+//	camembert | made in Normandy | easy | 3
+//	brie
+//
+// The category of text files defaults to the file name without extension, and the
+// language of all files to English. Categories are lower cased and their spaces
+// replaced by dashes, since players type them as a single word of their commands.
+// The alphabet of the pack is the predefined alphabet of its language unless the
+// file lists its letters.
+func LoadFile(path string) (*Pack, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p *Pack
+	if filepath.Ext(path) == ".json" {
+		p, err = decodeJSON(f)
+	} else {
+		p, err = decodeText(f, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	p.Category = normalizeCategory(p.Category)
+
+	err = p.resolveAlphabet()
+	if err == nil {
+		err = p.validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return p, nil
+}
+
+// normalizeCategory returns category as typed in commands, which are lower cased
+// and split on spaces.
+func normalizeCategory(category string) string {
+	return strings.Join(strings.Fields(strings.ToLower(category)), "-")
+}
+
+// decodeJSON reads a JSON pack from r.
+func decodeJSON(r io.Reader) (*Pack, error) {
+	var p Pack
+
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+
+	err := d.Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// decodeText reads a text pack from r. category is the category of the pack unless
+// a header sets it.
+func decodeText(r io.Reader, category string) (*Pack, error) {
+	p := Pack{Category: category}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			continue
+
+		case strings.HasPrefix(line, "#"):
+			err := p.setHeader(strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+
+		default:
+			fields := strings.Split(line, "|")
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+
+			w := Word{Text: fields[0]}
+			if len(fields) > 1 {
+				w.Clue = fields[1]
+			}
+			if len(fields) > 2 {
+				w.Difficulty = fields[2]
+			}
+			if len(fields) > 3 && fields[3] != "" {
+				weight, err := strconv.Atoi(fields[3])
+				if err != nil || weight < 1 {
+					return nil, fmt.Errorf("line %d: invalid weight %q", n, fields[3])
+				}
+				w.Weight = weight
+			}
+			if len(fields) > 4 {
+				return nil, fmt.Errorf("line %d: expected word | clue | difficulty | weight", n)
+			}
+
+			p.Words = append(p.Words, w)
+		}
+	}
+
+	return &p, scanner.Err()
+}
+
+// setHeader sets the pack setting described by a text header. Comments that are not
+// headers are ignored.
+func (p *Pack) setHeader(header string) error {
+	parts := strings.SplitN(header, ":", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	value := strings.TrimSpace(parts[1])
+
+	switch strings.TrimSpace(parts[0]) {
+	case "category":
+		p.Category = value
+
+	case "language":
+		p.Language = value
+
+	case "letters":
+		p.Alphabet.Letters = value
+
+	case "fold_accents":
+		fold, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid fold_accents value %q", value)
+		}
+		p.Alphabet.FoldAccents = fold
+	}
+
+	return nil
+}
+
+// resolveAlphabet sets the language of the pack, English by default, and its
+// alphabet, the predefined alphabet of the language by default.
+func (p *Pack) resolveAlphabet() error {
+	if p.Language == "" {
+		p.Language = game.English.Name
+	}
+
+	if p.Alphabet.Letters != "" {
+		if p.Alphabet.Name == "" {
+			p.Alphabet.Name = p.Language
+		}
+
+		return nil
+	}
+
+	a, ok := game.AlphabetFor(p.Language)
+	if !ok {
+		return fmt.Errorf("unknown language %q, packs in other languages must list the letters of their alphabet", p.Language)
+	}
+
+	p.Alphabet = a
+
+	return nil
+}
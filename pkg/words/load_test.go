@@ -0,0 +1,111 @@
+package words
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Popcore/hangmango/pkg/game"
+)
+
+// writeFiles writes files, indexed by name, to a new temporary directory and returns
+// its path.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "words")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"fromages.json": `{
+			"category": "Fromages",
+			"language": "fr",
+			"words": ["brie", {"word": "crème fraîche", "clue": "not a cheese", "difficulty": "hard"}]
+		}`,
+		"Villains.txt": `# villains of the cartoons
+joker | laughs a lot | easy | 3
+
+lex luthor
+`,
+		"greek.txt": `# category: Greek  Letters
+# language: el
+# letters: αβγδεζηθικλμνξοπρστυφχψω
+αλφα | | normal
+`,
+		"README.md": "not a pack",
+	})
+	defer os.RemoveAll(dir)
+
+	packs, err := LoadDir(dir)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// file names are listed in byte order, upper case first
+	assert.Equal(t, []*Pack{
+		{
+			Category: "villains",
+			Language: "en",
+			Alphabet: game.English,
+			Words: []Word{
+				{Text: "joker", Clue: "laughs a lot", Difficulty: "easy", Weight: 3},
+				{Text: "lex luthor"},
+			},
+		},
+		{
+			Category: "fromages",
+			Language: "fr",
+			Alphabet: game.French,
+			Words: []Word{
+				{Text: "brie"},
+				{Text: "crème fraîche", Clue: "not a cheese", Difficulty: "hard"},
+			},
+		},
+		{
+			Category: "greek-letters",
+			Language: "el",
+			Alphabet: game.Alphabet{Name: "el", Letters: "αβγδεζηθικλμνξοπρστυφχψω"},
+			Words:    []Word{{Text: "αλφα", Difficulty: "normal"}},
+		},
+	}, packs)
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	testcases := map[string]string{
+		"empty.txt":      "# nothing to guess\n",
+		"alphabet.txt":   "crème\n",
+		"language.txt":   "# language: el\nalpha\n",
+		"difficulty.txt": "batman | | extreme\n",
+		"weight.txt":     "batman | clue | easy | extra\n",
+		"fields.txt":     "batman | clue | easy | 1 | extra\n",
+		"negative.json":  `{"category": "heroes", "words": [{"word": "batman", "weight": -1}]}`,
+		"fold.txt":       "# fold_accents: maybe\nbatman\n",
+		"unknown.json":   `{"category": "heroes", "words": ["batman"], "extra": true}`,
+		"category.json":  `{"words": ["batman"]}`,
+	}
+
+	dir := writeFiles(t, testcases)
+	defer os.RemoveAll(dir)
+
+	for name := range testcases {
+		_, err := LoadFile(filepath.Join(dir, name))
+		assert.NotNil(t, err, name)
+	}
+
+	_, err := LoadDir(filepath.Join(dir, "missing"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,209 @@
+package words
+
+import (
+	"math"
+	"sort"
+
+	"github.com/Popcore/hangmango/pkg/game"
+)
+
+const (
+	// SourceAdaptive prefixes the name of the sources returned by Adapt.
+	SourceAdaptive = "adaptive"
+
+	// RecentGames is the number of finished games the recent win rate of a player is
+	// computed from.
+	RecentGames = 10
+
+	// longWord is the length from which words are no longer easier for being longer.
+	longWord = 15
+
+	// trustedPlays is the number of finished games after which the solve rate of a
+	// word weighs as much as its letters in its score.
+	trustedPlays = 5
+)
+
+// SolveStats counts the finished games of a word and how many of them were won.
+type SolveStats struct {
+	Played int `json:"played"`
+	Won    int `json:"won"`
+}
+
+// Stats returns the solve stats of the words of games, indexed by word. Games in
+// progress are ignored.
+func Stats(games []game.State) map[string]SolveStats {
+	stats := map[string]SolveStats{}
+	for _, g := range games {
+		if g.Status != game.Won && g.Status != game.GameOver {
+			continue
+		}
+
+		s := stats[g.WordToGuess]
+		s.Played++
+		if g.Status == game.Won {
+			s.Won++
+		}
+		stats[g.WordToGuess] = s
+	}
+
+	return stats
+}
+
+// RecentWinRate returns the share of the last RecentGames finished games won by a
+// player, given all the games of the player. Players without finished games have a
+// win rate of 0.5.
+func RecentWinRate(games []game.State) float64 {
+	sorted := make([]game.State, len(games))
+	copy(sorted, games)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].GameID > sorted[j].GameID
+	})
+
+	var finished, won int
+	for _, g := range sorted {
+		if finished == RecentGames {
+			break
+		}
+
+		switch g.Status {
+		case game.Won:
+			won++
+			finished++
+
+		case game.GameOver:
+			finished++
+		}
+	}
+
+	if finished == 0 {
+		return 0.5
+	}
+
+	return float64(won) / float64(finished)
+}
+
+// Score is the difficulty of a word, from 0 for the easiest words to 1 for the
+// hardest, and the solve stats it was computed from.
+type Score struct {
+	Word  Word       `json:"word"`
+	Score float64    `json:"score"`
+	Stats SolveStats `json:"stats"`
+}
+
+// Scores returns the difficulty scores of the words of the pack, hardest first. A
+// word is harder the shorter it is, the fewer letters it repeats and the rarer its
+// letters are among the words of the pack. As a word gets played its score moves
+// towards the share of its games lost, according to stats.
+func (p *Pack) Scores(stats map[string]SolveStats) []Score {
+	frequency := map[string]int{}
+	var mostFrequent int
+	for _, w := range p.Words {
+		for _, k := range p.Alphabet.Keys(w.Text) {
+			frequency[k]++
+			if frequency[k] > mostFrequent {
+				mostFrequent = frequency[k]
+			}
+		}
+	}
+
+	scores := make([]Score, 0, len(p.Words))
+	for _, w := range p.Words {
+		keys := p.Alphabet.Keys(w.Text)
+		if len(keys) == 0 {
+			continue
+		}
+
+		var length int
+		for _, r := range w.Text {
+			if p.Alphabet.Contains(r) {
+				length++
+			}
+		}
+
+		shortness := 1 - math.Min(float64(length), longWord)/longWord
+		distinct := float64(len(keys)) / float64(length)
+
+		var rarity float64
+		for _, k := range keys {
+			rarity += 1 - float64(frequency[k])/float64(mostFrequent)
+		}
+		rarity /= float64(len(keys))
+
+		score := (shortness + distinct + rarity) / 3
+
+		s := stats[w.Text]
+		if s.Played > 0 {
+			trust := float64(s.Played) / float64(s.Played+trustedPlays)
+			lost := 1 - float64(s.Won)/float64(s.Played)
+
+			score = (1-trust)*score + trust*lost
+		}
+
+		scores = append(scores, Score{Word: w, Score: score, Stats: s})
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+
+	return scores
+}
+
+// adaptive narrows the candidates down to the words whose difficulty matches a
+// target before picking one with another source.
+type adaptive struct {
+	source WordSource
+	scores map[string]float64
+	target float64
+}
+
+// Adapt returns a source that picks with source among a third of the candidates,
+// those whose rank from the easiest to the hardest, see Pack.Scores, matches target:
+// the easiest words for 0 and the hardest for 1. The scores and target are not
+// recorded by the games it starts, so its picks cannot be replayed and their seed
+// is always 0.
+func Adapt(source WordSource, scores []Score, target float64) WordSource {
+	index := make(map[string]float64, len(scores))
+	for _, s := range scores {
+		index[s.Word.Text] = s.Score
+	}
+
+	return &adaptive{source: source, scores: index, target: math.Max(0, math.Min(target, 1))}
+}
+
+func (a *adaptive) Name() string {
+	return SourceAdaptive + ":" + a.source.Name()
+}
+
+func (a *adaptive) Pick(candidates []Word) (Word, int64) {
+	w, _ := a.source.Pick(a.matching(candidates))
+
+	return w, 0
+}
+
+func (a *adaptive) Replay(candidates []Word, seed int64) Word {
+	return a.source.Replay(a.matching(candidates), seed)
+}
+
+// matching returns the third of the candidates, at least one, centered on the rank
+// of the target among the candidates sorted from the easiest to the hardest.
+func (a *adaptive) matching(candidates []Word) []Word {
+	sorted := make([]Word, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return a.scores[sorted[i].Text] < a.scores[sorted[j].Text]
+	})
+
+	n := (len(sorted) + 2) / 3
+	center := int(math.Round(a.target * float64(len(sorted)-1)))
+
+	start := center - n/2
+	if start > len(sorted)-n {
+		start = len(sorted) - n
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	return sorted[start : start+n]
+}
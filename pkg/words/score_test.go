@@ -0,0 +1,97 @@
+package words
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Popcore/hangmango/pkg/game"
+)
+
+func TestStats(t *testing.T) {
+	games := []game.State{
+		{WordToGuess: "batman", Status: game.Won},
+		{WordToGuess: "batman", Status: game.GameOver},
+		{WordToGuess: "batman", Status: game.InProgress},
+		{WordToGuess: "superman", Status: game.Won},
+	}
+
+	assert.Equal(t, map[string]SolveStats{
+		"batman":   {Played: 2, Won: 1},
+		"superman": {Played: 1, Won: 1},
+	}, Stats(games))
+}
+
+func TestRecentWinRate(t *testing.T) {
+	assert.Equal(t, 0.5, RecentWinRate(nil))
+
+	var games []game.State
+	for id := 1; id <= 20; id++ {
+		status := game.GameOver
+		if id > 12 {
+			status = game.Won
+		}
+
+		games = append(games, game.State{GameID: id, Status: status})
+	}
+	games = append(games, game.State{GameID: 21, Status: game.InProgress})
+
+	// the last 10 finished games are 11 to 20, 8 of which were won
+	assert.Equal(t, 0.8, RecentWinRate(games))
+}
+
+func TestPackScores(t *testing.T) {
+	p := &Pack{Category: "test", Alphabet: game.English}
+	for _, w := range []string{"banana", "bandana", "quiz", "cabana"} {
+		p.Words = append(p.Words, Word{Text: w})
+	}
+
+	scores := p.Scores(nil)
+	if assert.Len(t, scores, 4) {
+		assert.Equal(t, "quiz", scores[0].Word.Text)
+		assert.Equal(t, "banana", scores[3].Word.Text)
+	}
+
+	for _, s := range scores {
+		assert.True(t, s.Score >= 0 && s.Score <= 1, "%s: %f", s.Word.Text, s.Score)
+	}
+
+	scores = p.Scores(map[string]SolveStats{
+		"quiz":   {Played: 20, Won: 20},
+		"banana": {Played: 20},
+	})
+	assert.Equal(t, "banana", scores[0].Word.Text)
+	assert.Equal(t, SolveStats{Played: 20}, scores[0].Stats)
+	assert.Equal(t, "quiz", scores[3].Word.Text)
+}
+
+func TestAdapt(t *testing.T) {
+	p := &Pack{Category: "test", Alphabet: game.English}
+	for _, w := range []string{"banana", "bandana", "quiz", "cabana", "jazz", "mammal"} {
+		p.Words = append(p.Words, Word{Text: w})
+	}
+
+	scores := p.Scores(nil)
+	hardest := map[string]bool{scores[0].Word.Text: true, scores[1].Word.Text: true}
+	easiest := map[string]bool{scores[4].Word.Text: true, scores[5].Word.Text: true}
+
+	testcases := []struct {
+		target float64
+		want   map[string]bool
+	}{
+		{1, hardest},
+		{0, easiest},
+		{-3, easiest},
+	}
+
+	for _, tc := range testcases {
+		source := Adapt(NewUniform(), scores, tc.target)
+		assert.Equal(t, "adaptive:uniform", source.Name())
+
+		for i := 0; i < 20; i++ {
+			w, seed := source.Pick(p.Words)
+			assert.True(t, tc.want[w.Text], "target %f picked %s", tc.target, w.Text)
+			assert.Zero(t, seed)
+		}
+	}
+}
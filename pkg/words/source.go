@@ -0,0 +1,172 @@
+package words
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the word sources, as accepted by ParseSource.
+const (
+	SourceUniform    = "uniform"
+	SourceWeighted   = "weighted"
+	SourceSeeded     = "seeded"
+	SourceSequential = "sequential"
+)
+
+// WordSource picks the word to guess of a new game among the candidate words of a
+// pack. Pick returns the word picked and a seed such that Replay returns the same
+// word given the same candidates and seed, so that picks can be reproduced in tests.
+// Adaptive sources, see Adapt, are the exception.
+type WordSource interface {
+	Name() string
+	Pick(candidates []Word) (Word, int64)
+	Replay(candidates []Word, seed int64) Word
+}
+
+// ParseSource returns the word source described by s: "uniform", "weighted",
+// "sequential" or "seeded:<seed>".
+func ParseSource(s string) (WordSource, error) {
+	switch s {
+	case SourceUniform:
+		return NewUniform(), nil
+
+	case SourceWeighted:
+		return NewWeighted(), nil
+
+	case SourceSequential:
+		return NewSequential(), nil
+	}
+
+	if strings.HasPrefix(s, SourceSeeded+":") {
+		seed, err := strconv.ParseInt(strings.TrimPrefix(s, SourceSeeded+":"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed in word source %q", s)
+		}
+
+		return NewSeeded(seed), nil
+	}
+
+	return nil, fmt.Errorf("unknown word source %q. Valid sources are uniform, weighted, sequential and seeded:<seed>", s)
+}
+
+// seeds generates the seeds of the games. The embedded mutex ensures protected
+// concurrent access to its generator, which is not safe for concurrent use.
+type seeds struct {
+	sync.Mutex
+	r *rand.Rand
+}
+
+func newSeeds(seed int64) *seeds {
+	return &seeds{r: rand.New(rand.NewSource(seed))}
+}
+
+// next returns the seed of the next game.
+func (s *seeds) next() int64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.r.Int63()
+}
+
+// uniform picks every candidate with the same probability.
+type uniform struct {
+	name  string
+	seeds *seeds
+}
+
+// NewUniform returns a source that picks every candidate with the same probability.
+func NewUniform() WordSource {
+	return &uniform{name: SourceUniform, seeds: newSeeds(time.Now().UnixNano())}
+}
+
+// NewSeeded returns a source that picks every candidate with the same probability,
+// like NewUniform, but whose sequence of picks is determined by seed.
+func NewSeeded(seed int64) WordSource {
+	return &uniform{name: SourceSeeded, seeds: newSeeds(seed)}
+}
+
+func (u *uniform) Name() string {
+	return u.name
+}
+
+func (u *uniform) Pick(candidates []Word) (Word, int64) {
+	seed := u.seeds.next()
+
+	return u.Replay(candidates, seed), seed
+}
+
+func (u *uniform) Replay(candidates []Word, seed int64) Word {
+	return candidates[rand.New(rand.NewSource(seed)).Intn(len(candidates))]
+}
+
+// weighted picks candidates with a probability proportional to their weight.
+type weighted struct {
+	seeds *seeds
+}
+
+// NewWeighted returns a source that picks candidates with a probability proportional
+// to their frequency, see Word.Weight.
+func NewWeighted() WordSource {
+	return &weighted{seeds: newSeeds(time.Now().UnixNano())}
+}
+
+func (w *weighted) Name() string {
+	return SourceWeighted
+}
+
+func (w *weighted) Pick(candidates []Word) (Word, int64) {
+	seed := w.seeds.next()
+
+	return w.Replay(candidates, seed), seed
+}
+
+func (w *weighted) Replay(candidates []Word, seed int64) Word {
+	var total int
+	for _, c := range candidates {
+		total += c.weight()
+	}
+
+	n := rand.New(rand.NewSource(seed)).Intn(total)
+	for _, c := range candidates {
+		n -= c.weight()
+		if n < 0 {
+			return c
+		}
+	}
+
+	return candidates[len(candidates)-1]
+}
+
+// sequential picks the candidates in turn. The embedded mutex ensures protected
+// concurrent access to the number of picks.
+type sequential struct {
+	sync.Mutex
+	picks int64
+}
+
+// NewSequential returns a source that picks the candidates in order, starting over
+// once all of them have been picked.
+func NewSequential() WordSource {
+	return &sequential{}
+}
+
+func (s *sequential) Name() string {
+	return SourceSequential
+}
+
+func (s *sequential) Pick(candidates []Word) (Word, int64) {
+	s.Lock()
+	seed := s.picks
+	s.picks++
+	s.Unlock()
+
+	return s.Replay(candidates, seed), seed
+}
+
+func (s *sequential) Replay(candidates []Word, seed int64) Word {
+	return candidates[seed%int64(len(candidates))]
+}
@@ -0,0 +1,62 @@
+package words
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSource(t *testing.T) {
+	for _, s := range []string{"uniform", "weighted", "sequential"} {
+		source, err := ParseSource(s)
+		assert.Nil(t, err)
+		assert.Equal(t, s, source.Name())
+	}
+
+	source, err := ParseSource("seeded:42")
+	assert.Nil(t, err)
+	assert.Equal(t, SourceSeeded, source.Name())
+
+	for _, s := range []string{"", "random", "seeded", "seeded:abc"} {
+		_, err := ParseSource(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestSequentialSource(t *testing.T) {
+	candidates := []Word{{Text: "alpha"}, {Text: "bravo"}, {Text: "charlie"}}
+	source := NewSequential()
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		w, _ := source.Pick(candidates)
+		got = append(got, w.Text)
+	}
+
+	assert.Equal(t, []string{"alpha", "bravo", "charlie", "alpha"}, got)
+}
+
+func TestSeededSource(t *testing.T) {
+	candidates := []Word{{Text: "alpha"}, {Text: "bravo"}, {Text: "charlie"}, {Text: "delta"}}
+	first, second := NewSeeded(42), NewSeeded(42)
+
+	for i := 0; i < 10; i++ {
+		w1, seed1 := first.Pick(candidates)
+		w2, seed2 := second.Pick(candidates)
+		assert.Equal(t, w1, w2)
+		assert.Equal(t, seed1, seed2)
+	}
+}
+
+func TestWeightedSource(t *testing.T) {
+	candidates := []Word{{Text: "rare"}, {Text: "common", Weight: 99}, {Text: "default"}}
+	source := NewWeighted()
+
+	picks := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		w, _ := source.Pick(candidates)
+		picks[w.Text]++
+	}
+
+	assert.True(t, picks["common"] > 900, "common picked %d times", picks["common"])
+}
@@ -0,0 +1,258 @@
+package words
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Popcore/hangmango/pkg/game"
+)
+
+var (
+	ErrorNoWord = errors.New("no word of the category matches the difficulty of the game")
+)
+
+// Word is a word, or phrase, to guess. Clue is an optional clue a player can get as
+// a hint and Difficulty optionally restricts the word to the games of a difficulty
+// level. Weight is the relative frequency of the word for weighted word sources, 1
+// if not set.
+type Word struct {
+	Text       string `json:"word"`
+	Clue       string `json:"clue,omitempty"`
+	Difficulty string `json:"difficulty,omitempty"`
+	Weight     int    `json:"weight,omitempty"`
+}
+
+// weight returns the relative frequency of the word.
+func (w Word) weight() int {
+	if w.Weight <= 0 {
+		return 1
+	}
+
+	return w.Weight
+}
+
+// UnmarshalJSON is the Word implementation of the JSON Unmarshaler interface. Words
+// without metadata can be written as plain strings.
+func (w *Word) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*w = Word{Text: text}
+		return nil
+	}
+
+	type word Word
+	return json.Unmarshal(data, (*word)(w))
+}
+
+// Pack is a collection of words of the same category written in the same language.
+// Guesses are validated against the pack Alphabet.
+type Pack struct {
+	Category string        `json:"category"`
+	Language string        `json:"language"`
+	Alphabet game.Alphabet `json:"alphabet"`
+	Words    []Word        `json:"words"`
+}
+
+// Pick returns a word of the pack that can be played at difficulty, picked by
+// source among the words a player did not play yet, and the seed that reproduces
+// the pick, see Replay. played holds the words the player played, from the least to
+// the most recently played. Once the player played all the words that can be played
+// at difficulty, they are picked again in least-recently-played order.
+func (p *Pack) Pick(difficulty game.Difficulty, source WordSource, played []string) (Word, int64, error) {
+	candidates := unplayed(p.candidates(difficulty), played)
+	if len(candidates) == 0 {
+		return Word{}, 0, ErrorNoWord
+	}
+
+	w, seed := source.Pick(candidates)
+
+	return w, seed, nil
+}
+
+// Replay returns the word source picked with seed for a game of the given
+// difficulty and a player who played the words in played before, see Pick, provided
+// the words of the pack did not change since. It is meant for tests: games record
+// their source and seed but not the words their player had played, so replaying the
+// source and seed of a stored game alone can return another word.
+func (p *Pack) Replay(difficulty game.Difficulty, source WordSource, seed int64, played []string) (Word, error) {
+	candidates := unplayed(p.candidates(difficulty), played)
+	if len(candidates) == 0 {
+		return Word{}, ErrorNoWord
+	}
+
+	return source.Replay(candidates, seed), nil
+}
+
+// candidates returns the words of the pack that can be played at difficulty.
+func (p *Pack) candidates(difficulty game.Difficulty) []Word {
+	var candidates []Word
+	for _, w := range p.Words {
+		if fits(w, difficulty) {
+			candidates = append(candidates, w)
+		}
+	}
+
+	return candidates
+}
+
+// NewGame returns a new game of the given difficulty whose word to guess is picked
+// from the pack by source, avoiding the words in played, see Pick. The game records
+// the source and seed of the pick.
+func (p *Pack) NewGame(difficulty game.Difficulty, source WordSource, played []string) (game.State, error) {
+	w, seed, err := p.Pick(difficulty, source, played)
+	if err != nil {
+		return game.State{}, err
+	}
+
+	state := game.New(w.Text, difficulty, p.Alphabet)
+	state.Category = p.Category
+	state.Clue = w.Clue
+	state.Source = source.Name()
+	state.Seed = seed
+
+	return state, nil
+}
+
+// unplayed returns the candidates that are not in played, the words played so far
+// from the least to the most recently played. If all the candidates were played it
+// returns the least recently played one.
+func unplayed(candidates []Word, played []string) []Word {
+	if len(played) == 0 {
+		return candidates
+	}
+
+	lastPlayed := make(map[string]int, len(played))
+	for i, w := range played {
+		lastPlayed[w] = i
+	}
+
+	var left []Word
+	for _, c := range candidates {
+		if _, ok := lastPlayed[c.Text]; !ok {
+			left = append(left, c)
+		}
+	}
+
+	if len(left) > 0 || len(candidates) == 0 {
+		return left
+	}
+
+	oldest := candidates[0]
+	for _, c := range candidates[1:] {
+		if lastPlayed[c.Text] < lastPlayed[oldest.Text] {
+			oldest = c
+		}
+	}
+
+	return []Word{oldest}
+}
+
+// validate ensures the pack has a category and that its words are written in its
+// alphabet, target a known difficulty level and have no negative weight.
+func (p *Pack) validate() error {
+	if p.Category == "" {
+		return fmt.Errorf("the pack has no category")
+	}
+
+	if len(p.Words) == 0 {
+		return fmt.Errorf("the %s pack has no words", p.Category)
+	}
+
+	for _, w := range p.Words {
+		if w.Text == "" || !p.Alphabet.Valid(w.Text) {
+			return fmt.Errorf("word %q of the %s pack is not written in the %q alphabet", w.Text, p.Category, p.Alphabet.Name)
+		}
+
+		if w.Difficulty != "" && !isLevel(w.Difficulty) {
+			return fmt.Errorf("word %q of the %s pack has an unknown difficulty %q", w.Text, p.Category, w.Difficulty)
+		}
+
+		if w.Weight < 0 {
+			return fmt.Errorf("word %q of the %s pack has a negative weight", w.Text, p.Category)
+		}
+	}
+
+	return nil
+}
+
+// fits returns true if w can be played at difficulty. Words restricted to a
+// difficulty level are only played at that level, or in custom games if their
+// length fits.
+func fits(w Word, difficulty game.Difficulty) bool {
+	if w.Difficulty != "" && difficulty.Level != game.LevelCustom && w.Difficulty != difficulty.Level {
+		return false
+	}
+
+	return difficulty.Fits(w.Text)
+}
+
+// isLevel returns true if level is the name of a predefined difficulty level.
+func isLevel(level string) bool {
+	for _, d := range game.Levels {
+		if d.Level == level {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Library holds the word packs available to players, indexed by category. The
+// embedded mutex ensures protected concurrent access to its packs.
+type Library struct {
+	sync.RWMutex
+	packs map[string]*Pack
+}
+
+// NewLibrary returns a library holding the default pack and packs. Packs of the
+// same category replace each other, the last one wins.
+func NewLibrary(packs ...*Pack) *Library {
+	l := &Library{}
+	l.Replace(packs)
+
+	return l
+}
+
+// Replace replaces the packs of the library with the default pack and packs.
+func (l *Library) Replace(packs []*Pack) {
+	index := map[string]*Pack{Heroes.Category: Heroes}
+	for _, p := range packs {
+		index[p.Category] = p
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.packs = index
+}
+
+// Pack returns the pack of the given category, or the default pack if category
+// is empty.
+func (l *Library) Pack(category string) (*Pack, bool) {
+	if category == "" {
+		category = DefaultCategory
+	}
+
+	l.RLock()
+	defer l.RUnlock()
+
+	p, ok := l.packs[category]
+	return p, ok
+}
+
+// Categories returns the sorted categories of the packs in the library.
+func (l *Library) Categories() []string {
+	l.RLock()
+	defer l.RUnlock()
+
+	categories := make([]string, 0, len(l.packs))
+	for c := range l.packs {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+
+	return categories
+}
@@ -0,0 +1,136 @@
+package words
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Popcore/hangmango/pkg/game"
+)
+
+func TestWordUnmarshalJSON(t *testing.T) {
+	var got []Word
+	err := json.Unmarshal([]byte(`["brie", {"word": "camembert", "clue": "made in Normandy", "difficulty": "easy"}]`), &got)
+	assert.Nil(t, err)
+	assert.Equal(t, []Word{
+		{Text: "brie"},
+		{Text: "camembert", Clue: "made in Normandy", Difficulty: "easy"},
+	}, got)
+}
+
+func TestPackPick(t *testing.T) {
+	p := &Pack{
+		Category: "test",
+		Alphabet: game.English,
+		Words: []Word{
+			{Text: "short"},
+			{Text: "averyverylongword"},
+			{Text: "tagged", Difficulty: game.LevelHard},
+		},
+	}
+
+	source := NewUniform()
+
+	w, _, err := p.Pick(game.Easy, source, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "short", w.Text)
+
+	w, _, err = p.Pick(game.Hard, source, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "averyverylongword", w.Text)
+
+	custom, _ := game.ParseDifficulty("custom:min=6,max=6")
+	w, _, err = p.Pick(custom, source, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "tagged", w.Text)
+
+	custom, _ = game.ParseDifficulty("custom:min=30")
+	_, _, err = p.Pick(custom, source, nil)
+	assert.Equal(t, ErrorNoWord, err)
+}
+
+func TestPackNewGame(t *testing.T) {
+	p := &Pack{
+		Category: "fromages",
+		Alphabet: game.French,
+		Words:    []Word{{Text: "comté", Clue: "from the Jura"}},
+	}
+
+	state, err := p.NewGame(game.Normal, NewSequential(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "comté", state.WordToGuess)
+	assert.Equal(t, "fromages", state.Category)
+	assert.Equal(t, "from the Jura", state.Clue)
+	assert.Equal(t, game.French, state.Alphabet)
+	assert.Equal(t, game.InProgress, state.Status)
+	assert.Equal(t, SourceSequential, state.Source)
+	assert.Equal(t, int64(0), state.Seed)
+}
+
+func TestPackReplay(t *testing.T) {
+	p := &Pack{Category: "test", Alphabet: game.English}
+	for _, w := range []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot"} {
+		p.Words = append(p.Words, Word{Text: w})
+	}
+
+	for _, source := range []WordSource{NewUniform(), NewWeighted(), NewSeeded(42), NewSequential()} {
+		var played []string
+		for i := 0; i < 10; i++ {
+			state, err := p.NewGame(game.Normal, source, played)
+			assert.Nil(t, err)
+
+			w, err := p.Replay(game.Normal, source, state.Seed, played)
+			assert.Nil(t, err)
+			assert.Equal(t, state.WordToGuess, w.Text, source.Name())
+
+			played = append(played, state.WordToGuess)
+		}
+	}
+}
+
+func TestPackPickUnplayed(t *testing.T) {
+	p := &Pack{Category: "test", Alphabet: game.English}
+	for _, w := range []string{"alpha", "bravo", "charlie"} {
+		p.Words = append(p.Words, Word{Text: w})
+	}
+
+	source := NewUniform()
+
+	w, _, err := p.Pick(game.Normal, source, []string{"charlie", "alpha"})
+	assert.Nil(t, err)
+	assert.Equal(t, "bravo", w.Text)
+
+	// once the pack is used up words come back in least-recently-played order,
+	// whatever the words played from other packs
+	var played = []string{"bravo", "charlie", "batman", "alpha", "charlie"}
+	var got []string
+	for i := 0; i < 4; i++ {
+		w, _, err := p.Pick(game.Normal, source, played)
+		assert.Nil(t, err)
+
+		got = append(got, w.Text)
+		played = append(played, w.Text)
+	}
+
+	assert.Equal(t, []string{"bravo", "alpha", "charlie", "bravo"}, got)
+}
+
+func TestLibrary(t *testing.T) {
+	l := NewLibrary(&Pack{Category: "fromages"})
+
+	assert.Equal(t, []string{"fromages", "heroes"}, l.Categories())
+
+	p, ok := l.Pack("")
+	assert.True(t, ok)
+	assert.Equal(t, Heroes, p)
+
+	_, ok = l.Pack("fromages")
+	assert.True(t, ok)
+
+	l.Replace([]*Pack{{Category: "villains"}})
+	assert.Equal(t, []string{"heroes", "villains"}, l.Categories())
+
+	_, ok = l.Pack("fromages")
+	assert.False(t, ok)
+}